@@ -0,0 +1,320 @@
+// Package diagnostics holds the environment checks `ollmlx doctor` reports
+// on, factored out of cmd.DoctorHandler so `ollmlx serve` can run the same
+// checks at startup and refuse to boot with a clear error instead of
+// failing at the first MLX request.
+package diagnostics
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+
+	"github.com/ollama/ollama/envconfig"
+)
+
+// Status is the outcome of a single Check.
+type Status string
+
+const (
+	StatusOK    Status = "ok"
+	StatusWarn  Status = "warn"
+	StatusError Status = "error"
+)
+
+// Result is one check's outcome, machine-readable enough to serialize as
+// the `doctor --format=json` report, and human-readable enough to print as
+// one status line plus optional detail lines.
+type Result struct {
+	Name    string   `json:"name"`
+	Status  Status   `json:"status"`
+	Message string   `json:"message"`
+	Detail  []string `json:"detail,omitempty"`
+}
+
+// Report is the full set of checks `ollmlx doctor` runs, in the order they
+// ran.
+type Report struct {
+	Results []Result `json:"results"`
+}
+
+// OK reports whether every check in the report passed or only warned; a
+// single StatusError fails the report, matching how `ollmlx serve` decides
+// whether to refuse to boot.
+func (r *Report) OK() bool {
+	for _, res := range r.Results {
+		if res.Status == StatusError {
+			return false
+		}
+	}
+	return true
+}
+
+// JSON renders the report the way `doctor --format=json` does.
+func (r *Report) JSON() ([]byte, error) {
+	return json.MarshalIndent(r, "", "  ")
+}
+
+// PythonInfo is the interpreter DetectPython decided ollmlx should use, and
+// where that decision came from.
+type PythonInfo struct {
+	Path   string
+	Source string // "OLLAMA_PYTHON", "ApplicationSupport", "venv", or "system"
+}
+
+// ApplicationSupportVenvDir is where Ollmlx.app's installer creates its
+// bundled Python environment.
+func ApplicationSupportVenvDir() (string, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(home, "Library", "Application Support", "Ollmlx", "venv"), nil
+}
+
+// legacyVenvDir is the dev/legacy dotfile location DetectPython still checks
+// for users who installed before Ollmlx.app existed.
+func legacyVenvDir() (string, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(home, ".ollmlx", "venv"), nil
+}
+
+// DetectPython picks the python3 interpreter ollmlx should use: an explicit
+// OLLAMA_PYTHON override, then the Ollmlx.app bundled venv, then the legacy
+// dotfile venv, falling back to whatever "python3" resolves to on PATH.
+func DetectPython() PythonInfo {
+	if p := os.Getenv("OLLAMA_PYTHON"); p != "" {
+		return PythonInfo{Path: p, Source: "OLLAMA_PYTHON"}
+	}
+
+	if dir, err := ApplicationSupportVenvDir(); err == nil {
+		bin := filepath.Join(dir, "bin", "python3")
+		if _, err := os.Stat(bin); err == nil {
+			return PythonInfo{Path: bin, Source: "ApplicationSupport"}
+		}
+	}
+
+	if dir, err := legacyVenvDir(); err == nil {
+		bin := filepath.Join(dir, "bin", "python3")
+		if _, err := os.Stat(bin); err == nil {
+			return PythonInfo{Path: bin, Source: "venv"}
+		}
+	}
+
+	return PythonInfo{Path: "python3", Source: "system"}
+}
+
+// pythonCheckScript is the same probe doctor.go has always run: it fails
+// with a non-zero exit if Python is older than 3.10 or the mlx package
+// isn't importable.
+const pythonCheckScript = `
+import sys
+import importlib.util
+
+v = sys.version_info
+print(f"Python {v.major}.{v.minor}.{v.micro}")
+
+if v < (3, 10):
+    print("FAIL: Python 3.10+ required")
+    sys.exit(1)
+
+if importlib.util.find_spec("mlx") is None:
+    print("FAIL: mlx not installed")
+    sys.exit(1)
+else:
+    import mlx.core as mx
+    print("OK: MLX installed")
+`
+
+// CheckOS reports whether the current platform is MLX's intended target,
+// Apple Silicon macOS.
+func CheckOS() Result {
+	switch {
+	case runtime.GOOS == "darwin" && runtime.GOARCH == "arm64":
+		return Result{Name: "os", Status: StatusOK, Message: "macOS on Apple Silicon"}
+	case runtime.GOOS == "darwin":
+		return Result{Name: "os", Status: StatusWarn, Message: fmt.Sprintf("macOS (%s) — MLX works best on Apple Silicon", runtime.GOARCH)}
+	default:
+		return Result{Name: "os", Status: StatusWarn, Message: fmt.Sprintf("%s/%s — MLX is optimized for Apple Silicon", runtime.GOOS, runtime.GOARCH)}
+	}
+}
+
+// CheckGo reports the Go toolchain version, if any; Go is optional at
+// runtime (only needed to build ollmlx itself).
+func CheckGo() Result {
+	goPath, err := exec.LookPath("go")
+	if err != nil {
+		return Result{Name: "go", Status: StatusWarn, Message: "Go not found (optional, for building)"}
+	}
+
+	out, _ := exec.Command(goPath, "version").Output()
+	version := strings.TrimPrefix(strings.TrimSpace(string(out)), "go version ")
+	return Result{Name: "go", Status: StatusOK, Message: version}
+}
+
+// CheckPython runs pythonCheckScript against info.Path and reports whether
+// it's new enough and has mlx installed.
+func CheckPython(info PythonInfo) Result {
+	out, err := exec.Command(info.Path, "-c", pythonCheckScript).CombinedOutput()
+	output := strings.TrimSpace(string(out))
+	lines := strings.Split(output, "\n")
+
+	if err != nil {
+		return Result{
+			Name:    "python",
+			Status:  StatusError,
+			Message: fmt.Sprintf("Python at %s (%s)", info.Path, info.Source),
+			Detail:  lines,
+		}
+	}
+
+	return Result{
+		Name:    "python",
+		Status:  StatusOK,
+		Message: fmt.Sprintf("%s (%s)", lines[0], info.Source),
+	}
+}
+
+// CheckXcodeCLT reports whether Xcode Command Line Tools are installed. On
+// non-macOS platforms it's always OK: CLT is a macOS-only prerequisite.
+func CheckXcodeCLT() Result {
+	if runtime.GOOS != "darwin" {
+		return Result{Name: "xcode-clt", Status: StatusOK, Message: "not applicable on " + runtime.GOOS}
+	}
+
+	if err := exec.Command("xcode-select", "-p").Run(); err != nil {
+		return Result{Name: "xcode-clt", Status: StatusError, Message: "Xcode Command Line Tools not found"}
+	}
+	return Result{Name: "xcode-clt", Status: StatusOK, Message: "Xcode Command Line Tools installed"}
+}
+
+// CheckModelsDir reports where ollmlx will look for downloaded models.
+func CheckModelsDir() Result {
+	return Result{Name: "models", Status: StatusOK, Message: fmt.Sprintf("Models: %s", envconfig.Models())}
+}
+
+// Run executes every check in the fixed order `ollmlx doctor` has always
+// reported them in.
+func Run() *Report {
+	return &Report{Results: []Result{
+		CheckOS(),
+		CheckGo(),
+		CheckXcodeCLT(),
+		CheckPython(DetectPython()),
+		CheckModelsDir(),
+	}}
+}
+
+// RunStartupCheck runs the checks that matter for actually serving MLX
+// models (Python and its mlx install) and returns a single error summarizing
+// any failures, for `ollmlx serve` to refuse to boot on rather than fail at
+// the first request.
+func RunStartupCheck(ctx context.Context) error {
+	results := []Result{CheckPython(DetectPython())}
+
+	var failures []string
+	for _, res := range results {
+		if res.Status == StatusError {
+			failures = append(failures, fmt.Sprintf("%s: %s", res.Name, res.Message))
+		}
+	}
+	if len(failures) > 0 {
+		return fmt.Errorf("ollmlx environment is not ready (run `ollmlx doctor --fix`): %s", strings.Join(failures, "; "))
+	}
+	return nil
+}
+
+// Fix attempts to remediate every failing check in report it knows how to
+// fix (currently: a missing/broken Python+mlx environment, and missing
+// Xcode Command Line Tools on macOS), returning one error per remediation
+// that was attempted and failed.
+func Fix(ctx context.Context, report *Report) []error {
+	var errs []error
+	for _, res := range report.Results {
+		if res.Status != StatusError {
+			continue
+		}
+		switch res.Name {
+		case "python":
+			if err := FixPythonEnv(ctx); err != nil {
+				errs = append(errs, fmt.Errorf("failed to repair python environment: %w", err))
+			}
+		case "xcode-clt":
+			if err := FixXcodeCLT(ctx); err != nil {
+				errs = append(errs, fmt.Errorf("failed to install Xcode Command Line Tools: %w", err))
+			}
+		}
+	}
+	return errs
+}
+
+// FixPythonEnv creates the Ollmlx.app-managed venv (if missing) with
+// `python3 -m venv`, then installs mlx_backend/requirements.txt into it.
+func FixPythonEnv(ctx context.Context) error {
+	dir, err := ApplicationSupportVenvDir()
+	if err != nil {
+		return err
+	}
+
+	pythonBin := filepath.Join(dir, "bin", "python3")
+	if _, err := os.Stat(pythonBin); err != nil {
+		if err := os.MkdirAll(filepath.Dir(dir), 0755); err != nil {
+			return fmt.Errorf("failed to create %s: %w", dir, err)
+		}
+		cmd := exec.CommandContext(ctx, "python3", "-m", "venv", dir)
+		if out, err := cmd.CombinedOutput(); err != nil {
+			return fmt.Errorf("python3 -m venv %s: %w: %s", dir, err, strings.TrimSpace(string(out)))
+		}
+	}
+
+	requirements := filepath.Join("mlx_backend", "requirements.txt")
+	if _, err := os.Stat(requirements); err != nil {
+		return fmt.Errorf("%s not found; run this from the ollmlx source tree", requirements)
+	}
+
+	cmd := exec.CommandContext(ctx, pythonBin, "-m", "pip", "install", "-r", requirements)
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("pip install -r %s: %w: %s", requirements, err, strings.TrimSpace(string(out)))
+	}
+	return nil
+}
+
+// FixXcodeCLT triggers the interactive Xcode Command Line Tools installer.
+// The install itself happens in a GUI dialog macOS pops up; this only kicks
+// it off.
+func FixXcodeCLT(ctx context.Context) error {
+	cmd := exec.CommandContext(ctx, "xcode-select", "--install")
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("xcode-select --install: %w: %s", err, strings.TrimSpace(string(out)))
+	}
+	return nil
+}
+
+// ParseFormat validates the `doctor --format` flag, defaulting to "text".
+func ParseFormat(format string) (string, error) {
+	switch format {
+	case "", "text":
+		return "text", nil
+	case "json":
+		return "json", nil
+	default:
+		return "", fmt.Errorf("unknown format %q (want \"text\" or \"json\")", format)
+	}
+}
+
+// ExitCode maps a Report to a process exit code: 0 if every check passed or
+// only warned, 1 if any check errored. Kept here (rather than in cmd) so
+// `serve`'s startup check and `doctor` agree on what counts as failing.
+func ExitCode(report *Report) int {
+	if report.OK() {
+		return 0
+	}
+	return 1
+}
@@ -3,14 +3,10 @@ package cmd
 import (
 	"fmt"
 	"os"
-	"os/exec"
-	"path/filepath"
-	"runtime"
-	"strings"
 
 	"github.com/spf13/cobra"
 
-	"github.com/ollama/ollama/envconfig"
+	"github.com/ollama/ollama/cmd/diagnostics"
 	"github.com/ollama/ollama/readline"
 )
 
@@ -18,7 +14,24 @@ var doctorCmd = &cobra.Command{
 	Use:   "doctor",
 	Short: "Check your environment for ollmlx readiness",
 	Long:  "Check your environment for ollmlx readiness: OS/Arch, Go, Python, and MLX dependencies.",
-	Run:   DoctorHandler,
+	RunE:  DoctorHandler,
+}
+
+var doctorRepairCmd = &cobra.Command{
+	Use:   "repair",
+	Short: "Check your environment and fix what it can",
+	Long:  "Equivalent to `ollmlx doctor --fix`: checks your environment and repairs what it can (Python venv, MLX install, Xcode Command Line Tools).",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cmd.Flags().Set("fix", "true")
+		return DoctorHandler(cmd, args)
+	},
+}
+
+func init() {
+	doctorCmd.Flags().Bool("fix", false, "attempt to repair any failing checks")
+	doctorCmd.Flags().String("format", "text", `output format: "text" or "json"`)
+	doctorRepairCmd.Flags().AddFlagSet(doctorCmd.Flags())
+	doctorCmd.AddCommand(doctorRepairCmd)
 }
 
 // Status indicators - clean text-based
@@ -34,113 +47,66 @@ func statusErr(msg string) {
 	fmt.Printf("  %s[x]%s   %s\n", readline.ColorError, readline.ColorDefault, msg)
 }
 
-func statusDim(msg string) {
-	fmt.Printf("  %s[-]%s   %s%s%s\n", readline.ColorMuted, readline.ColorDefault, readline.ColorMuted, msg, readline.ColorDefault)
-}
+func DoctorHandler(cmd *cobra.Command, args []string) error {
+	fix, _ := cmd.Flags().GetBool("fix")
+	rawFormat, _ := cmd.Flags().GetString("format")
+	format, err := diagnostics.ParseFormat(rawFormat)
+	if err != nil {
+		return err
+	}
 
-func DoctorHandler(cmd *cobra.Command, args []string) {
-	fmt.Println()
-	fmt.Printf("  %sollmlx%s  %sSystem Check%s\n", readline.ColorBold, readline.ColorDefault, readline.ColorMuted, readline.ColorDefault)
-	fmt.Printf("  %s────────────────────────────────%s\n", readline.ColorMuted, readline.ColorDefault)
-	fmt.Println()
+	report := diagnostics.Run()
 
-	// 1. Check OS/Arch
-	if runtime.GOOS == "darwin" && runtime.GOARCH == "arm64" {
-		statusOK("macOS on Apple Silicon")
-	} else if runtime.GOOS == "darwin" {
-		statusWarn(fmt.Sprintf("macOS (%s) — MLX works best on Apple Silicon", runtime.GOARCH))
-	} else {
-		statusWarn(fmt.Sprintf("%s/%s — MLX is optimized for Apple Silicon", runtime.GOOS, runtime.GOARCH))
+	if fix && !report.OK() {
+		for _, fixErr := range diagnostics.Fix(cmd.Context(), report) {
+			fmt.Fprintln(os.Stderr, "repair:", fixErr)
+		}
+		report = diagnostics.Run()
 	}
 
-	// 2. Check Go
-	if goPath, err := exec.LookPath("go"); err == nil {
-		out, _ := exec.Command(goPath, "version").Output()
-		version := strings.TrimPrefix(strings.TrimSpace(string(out)), "go version ")
-		statusOK(version)
+	if format == "json" {
+		data, err := report.JSON()
+		if err != nil {
+			return err
+		}
+		fmt.Println(string(data))
 	} else {
-		statusDim("Go not found (optional, for building)")
+		printReport(report)
 	}
 
-	// 3. Check Python & MLX
-	
-	// Logic matches server/routes_mlx.go
-	pythonPath := "python3"
-	source := "system"
-	
-	if p := os.Getenv("OLLAMA_PYTHON"); p != "" {
-		pythonPath = p
-		source = "OLLAMA_PYTHON"
-	} else {
-		home, err := os.UserHomeDir()
-		if err == nil {
-			// Priority 1: Application Support (Ollmlx.app standard)
-			appSupport := filepath.Join(home, "Library", "Application Support", "Ollmlx", "venv", "bin", "python3")
-			// Priority 2: Dotfile (Legacy/Dev)
-			dotFile := filepath.Join(home, ".ollmlx", "venv", "bin", "python3")
-
-			if _, err := os.Stat(appSupport); err == nil {
-				pythonPath = appSupport
-				source = "ApplicationSupport"
-			} else if _, err := os.Stat(dotFile); err == nil {
-				pythonPath = dotFile
-				source = "venv"
-			}
-		}
+	if !report.OK() {
+		os.Exit(diagnostics.ExitCode(report))
 	}
+	return nil
+}
 
-	// Verify Python version and MLX
-	checkScript := `
-import sys
-import importlib.util
-
-v = sys.version_info
-print(f"Python {v.major}.{v.minor}.{v.micro}")
-
-if v < (3, 10):
-    print("FAIL: Python 3.10+ required")
-    sys.exit(1)
-
-if importlib.util.find_spec("mlx") is None:
-    print("FAIL: mlx not installed")
-    sys.exit(1)
-else:
-    import mlx.core as mx
-    print("OK: MLX installed")
-`
-
-	cmdOut, err := exec.Command(pythonPath, "-c", checkScript).CombinedOutput()
-	output := strings.TrimSpace(string(cmdOut))
+func printReport(report *diagnostics.Report) {
+	fmt.Println()
+	fmt.Printf("  %sollmlx%s  %sSystem Check%s\n", readline.ColorBold, readline.ColorDefault, readline.ColorMuted, readline.ColorDefault)
+	fmt.Printf("  %s────────────────────────────────%s\n", readline.ColorMuted, readline.ColorDefault)
+	fmt.Println()
 
-	if err != nil {
-		statusErr(fmt.Sprintf("Python at %s (%s)", pythonPath, source))
-		if len(output) > 0 {
-			lines := strings.Split(output, "\n")
-			for _, l := range lines {
-				fmt.Printf("       %s%s%s\n", readline.ColorMuted, l, readline.ColorDefault)
-			}
+	for _, res := range report.Results {
+		switch res.Status {
+		case diagnostics.StatusOK:
+			statusOK(res.Message)
+		case diagnostics.StatusWarn:
+			statusWarn(res.Message)
+		case diagnostics.StatusError:
+			statusErr(res.Message)
 		}
-		if source == "venv" {
-			fmt.Printf("\n       %sTry: ./scripts/install_ollmlx.sh%s\n", readline.ColorMuted, readline.ColorDefault)
-		} else if source == "system" {
-			fmt.Printf("\n       %sTry: pip install -r mlx_backend/requirements.txt%s\n", readline.ColorMuted, readline.ColorDefault)
-		}
-	} else {
-		lines := strings.Split(output, "\n")
-		statusOK(fmt.Sprintf("%s (%s)", lines[0], source))
-
-		// MLX status
-		if len(lines) > 1 && strings.HasPrefix(lines[1], "OK:") {
-			statusOK("MLX installed")
+		for _, l := range res.Detail {
+			fmt.Printf("       %s%s%s\n", readline.ColorMuted, l, readline.ColorDefault)
 		}
 	}
 
-	// 4. Check Environment
-	statusOK(fmt.Sprintf("Models: %s", envconfig.Models()))
-
 	fmt.Println()
 	fmt.Printf("  %s────────────────────────────────%s\n", readline.ColorMuted, readline.ColorDefault)
 	fmt.Println()
-	fmt.Printf("  %sRun:%s  ollmlx serve\n", readline.ColorMuted, readline.ColorDefault)
+	if report.OK() {
+		fmt.Printf("  %sRun:%s  ollmlx serve\n", readline.ColorMuted, readline.ColorDefault)
+	} else {
+		fmt.Printf("  %sRun:%s  ollmlx doctor --fix\n", readline.ColorMuted, readline.ColorDefault)
+	}
 	fmt.Println()
 }
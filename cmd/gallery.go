@@ -0,0 +1,91 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"github.com/ollama/ollama/llm"
+)
+
+var galleryCmd = &cobra.Command{
+	Use:   "gallery",
+	Short: "Browse the curated MLX model gallery",
+	Long:  "Browse the curated MLX model gallery: short names like gemma3:270m-4bit that resolve to a full HuggingFace repo plus ollmlx-tuned defaults.",
+}
+
+var galleryListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List every model in the gallery",
+	RunE:  galleryListHandler,
+}
+
+var gallerySearchCmd = &cobra.Command{
+	Use:   "search <query>",
+	Short: "Search the gallery by name, repo, or description",
+	Args:  cobra.ExactArgs(1),
+	RunE:  gallerySearchHandler,
+}
+
+var galleryInfoCmd = &cobra.Command{
+	Use:   "info <name>",
+	Short: "Show gallery metadata for a curated model name",
+	Args:  cobra.ExactArgs(1),
+	RunE:  galleryInfoHandler,
+}
+
+func init() {
+	galleryCmd.AddCommand(galleryListCmd, gallerySearchCmd, galleryInfoCmd)
+}
+
+func galleryListHandler(cmd *cobra.Command, args []string) error {
+	return printGalleryEntries("")
+}
+
+func gallerySearchHandler(cmd *cobra.Command, args []string) error {
+	return printGalleryEntries(args[0])
+}
+
+func printGalleryEntries(query string) error {
+	catalog, err := llm.LoadMLXCatalog(context.Background())
+	if err != nil {
+		fmt.Println("warning: some galleries failed to load:", err)
+	}
+
+	for _, e := range catalog.Search(query) {
+		fmt.Printf("%-24s %s\n", e.Name, e.Repo)
+	}
+	return nil
+}
+
+func galleryInfoHandler(cmd *cobra.Command, args []string) error {
+	catalog, err := llm.LoadMLXCatalog(context.Background())
+	if err != nil {
+		fmt.Println("warning: some galleries failed to load:", err)
+	}
+
+	entry, ok := catalog.Find(args[0])
+	if !ok {
+		return fmt.Errorf("%q is not a known gallery name", args[0])
+	}
+
+	fmt.Printf("name:            %s\n", entry.Name)
+	fmt.Printf("repo:            %s\n", entry.Repo)
+	if entry.Description != "" {
+		fmt.Printf("description:     %s\n", entry.Description)
+	}
+	if entry.MinMLXVersion != "" {
+		fmt.Printf("min mlx version: %s\n", entry.MinMLXVersion)
+	}
+	if entry.Quantization != "" {
+		fmt.Printf("quantization:    %s\n", entry.Quantization)
+	}
+	if entry.ContextLength > 0 {
+		fmt.Printf("context length:  %d\n", entry.ContextLength)
+	}
+	if entry.PromptTemplate != "" {
+		fmt.Printf("prompt template: %s\n", entry.PromptTemplate)
+	}
+	return nil
+}
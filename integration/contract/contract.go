@@ -0,0 +1,121 @@
+// Package contract holds golden-file structural-shape tests for the JSON
+// ollmlx's MLX backend emits from /api/generate, /api/chat, and
+// /v1/embeddings. This checkout has no GGUF backend to capture a canonical
+// payload from (see api.GenerateResponse/api.ChatResponse in the base
+// Ollama types this overlay builds on, and collectMLXGenerateResponse and
+// friends in server/routes_mlx.go), so the golden files under testdata/ are
+// hand-authored from those shared types' own field definitions instead of
+// captured from a live GGUF run: they encode the backend-agnostic contract
+// every backend is expected to honor, not one backend's literal output.
+//
+// Diff treats a golden file as a lower bound: any key it declares must be
+// present in the actual response with a compatible JSON type, but an actual
+// response is free to include extra keys a golden file doesn't mention.
+// That asymmetry is deliberate — it's what lets the same golden file cover
+// both a minimal backend and a richer one without flagging the richer one
+// as broken.
+package contract
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+)
+
+// Diff compares the JSON contract in the file at goldenPath against actual,
+// returning one human-readable line per key that's missing or has drifted
+// to an incompatible type in actual. A nil result means actual satisfies
+// the contract.
+func Diff(goldenPath string, actual []byte) ([]string, error) {
+	golden, err := os.ReadFile(goldenPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read golden file %s: %w", goldenPath, err)
+	}
+
+	var want, got any
+	if err := json.Unmarshal(golden, &want); err != nil {
+		return nil, fmt.Errorf("failed to parse golden file %s: %w", goldenPath, err)
+	}
+	if err := json.Unmarshal(actual, &got); err != nil {
+		return nil, fmt.Errorf("failed to parse actual response: %w", err)
+	}
+
+	var diffs []string
+	compare("$", want, got, &diffs)
+	return diffs, nil
+}
+
+// compare recursively checks that got satisfies the shape want declares at
+// path, appending one line to diffs per violation. A null in want means
+// "present, value not constrained" and skips the type check entirely, the
+// same way an omitempty field's zero value does in the real structs.
+func compare(path string, want, got any, diffs *[]string) {
+	if want == nil {
+		return
+	}
+
+	switch w := want.(type) {
+	case map[string]any:
+		g, ok := got.(map[string]any)
+		if !ok {
+			*diffs = append(*diffs, fmt.Sprintf("%s: want object, got %s", path, typeName(got)))
+			return
+		}
+
+		keys := make([]string, 0, len(w))
+		for k := range w {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+
+		for _, k := range keys {
+			gv, present := g[k]
+			if !present {
+				*diffs = append(*diffs, fmt.Sprintf("%s.%s: missing", path, k))
+				continue
+			}
+			compare(path+"."+k, w[k], gv, diffs)
+		}
+	case []any:
+		g, ok := got.([]any)
+		if !ok {
+			*diffs = append(*diffs, fmt.Sprintf("%s: want array, got %s", path, typeName(got)))
+			return
+		}
+		if len(w) == 0 {
+			return
+		}
+		if len(g) == 0 {
+			*diffs = append(*diffs, fmt.Sprintf("%s: want non-empty array, got empty", path))
+			return
+		}
+		// Golden arrays only ever describe one representative element
+		// (the first); comparing every element of actual against it
+		// would just repeat the same check len(g) times.
+		compare(path+"[0]", w[0], g[0], diffs)
+	default:
+		if typeName(want) != typeName(got) {
+			*diffs = append(*diffs, fmt.Sprintf("%s: want %s, got %s", path, typeName(want), typeName(got)))
+		}
+	}
+}
+
+func typeName(v any) string {
+	switch v.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "bool"
+	case float64:
+		return "number"
+	case string:
+		return "string"
+	case map[string]any:
+		return "object"
+	case []any:
+		return "array"
+	default:
+		return fmt.Sprintf("%T", v)
+	}
+}
@@ -0,0 +1,231 @@
+package integration
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"image"
+	"image/color"
+	"image/png"
+	"io"
+	"net/http"
+	"testing"
+
+	"github.com/ollama/ollama/integration/contract"
+)
+
+// contractGoldenDir holds the canonical response-shape contracts this test
+// diffs the MLX backend's actual JSON against. See contract.go for why
+// they're hand-authored rather than captured from a live GGUF run.
+const contractGoldenDir = "contract/testdata"
+
+// TestMLXResponseContract drives the same requests TestMLXvsGGUFResponseFormat
+// already exercises through the hermetic MLX harness, but instead of
+// spot-checking that individual fields are non-zero it diffs the raw JSON
+// ollmlx emits against the golden contract files under testdata/: every key
+// a golden file declares must be present with a compatible JSON type. Extra
+// keys in the actual response are allowed, and neither key order nor the
+// order of done/done_reason/context is compared. A failure prints the
+// structural diff so drift between
+// backends is caught here instead of downstream in a client.
+func TestMLXResponseContract(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping contract test in short mode")
+	}
+	h := requireHarness(t)
+
+	t.Run("generate", func(t *testing.T) {
+		body := contractPost(t, h.BaseURL()+"/api/generate", map[string]any{
+			"model":  compatModel,
+			"prompt": "Why is the sky blue?",
+			"stream": false,
+		})
+		assertContract(t, "generate_response.golden.json", body)
+	})
+
+	t.Run("generate_stream", func(t *testing.T) {
+		lines := contractStream(t, h.BaseURL()+"/api/generate", map[string]any{
+			"model":  compatModel,
+			"prompt": "Why is the sky blue?",
+			"stream": true,
+		})
+		assertContract(t, "generate_stream_first.golden.json", lines[0])
+		assertContract(t, "generate_stream_terminal.golden.json", lines[len(lines)-1])
+	})
+
+	t.Run("chat", func(t *testing.T) {
+		body := contractPost(t, h.BaseURL()+"/api/chat", map[string]any{
+			"model":    compatModel,
+			"messages": []map[string]string{{"role": "user", "content": "Why is the sky blue?"}},
+			"stream":   false,
+		})
+		assertContract(t, "chat_response.golden.json", body)
+	})
+
+	t.Run("chat_stream", func(t *testing.T) {
+		lines := contractStream(t, h.BaseURL()+"/api/chat", map[string]any{
+			"model":    compatModel,
+			"messages": []map[string]string{{"role": "user", "content": "Why is the sky blue?"}},
+			"stream":   true,
+		})
+		assertContract(t, "chat_stream_first.golden.json", lines[0])
+		assertContract(t, "chat_stream_terminal.golden.json", lines[len(lines)-1])
+	})
+
+	t.Run("chat_with_tools", func(t *testing.T) {
+		body := contractPost(t, h.BaseURL()+"/api/chat", map[string]any{
+			"model": compatModel,
+			"messages": []map[string]string{
+				{"role": "user", "content": "What's the weather in Boston?"},
+			},
+			"tools":  []any{contractWeatherTool},
+			"stream": false,
+		})
+		assertContract(t, "chat_response_tools.golden.json", body)
+	})
+
+	t.Run("chat_with_image", func(t *testing.T) {
+		body := contractPost(t, h.BaseURL()+"/api/chat", map[string]any{
+			"model": compatModel,
+			"messages": []map[string]any{{
+				"role":    "user",
+				"content": "What's in this image?",
+				"images":  []string{base64.StdEncoding.EncodeToString(newContractPNG(t))},
+			}},
+			"stream": false,
+		})
+		assertContract(t, "chat_response.golden.json", body)
+	})
+
+	t.Run("embeddings", func(t *testing.T) {
+		body := contractPost(t, h.BaseURL()+"/v1/embeddings", map[string]any{
+			"model": compatModel,
+			"input": []string{"Why is the sky blue?"},
+		})
+		assertContract(t, "embeddings_response.golden.json", body)
+	})
+}
+
+// contractWeatherTool is the tool definition used by the chat_with_tools
+// case, shaped like the OpenAI-style tool an Ollama client would send.
+var contractWeatherTool = map[string]any{
+	"type": "function",
+	"function": map[string]any{
+		"name":        "get_weather",
+		"description": "Get the current weather for a location",
+		"parameters": map[string]any{
+			"type":     "object",
+			"required": []string{"location"},
+			"properties": map[string]any{
+				"location": map[string]any{
+					"type":        "string",
+					"description": "The city to get the weather for",
+				},
+			},
+		},
+	},
+}
+
+// assertContract diffs body against the golden file goldenName under
+// contract/testdata, failing t with a line per structural violation.
+func assertContract(t *testing.T, goldenName string, body []byte) {
+	t.Helper()
+	diffs, err := contract.Diff(contractGoldenDir+"/"+goldenName, body)
+	if err != nil {
+		t.Fatalf("failed to diff against %s: %v", goldenName, err)
+	}
+	for _, d := range diffs {
+		t.Errorf("%s: %s", goldenName, d)
+	}
+}
+
+// contractPost POSTs body as JSON to url and returns the raw response
+// bytes, so the contract diff sees exactly what went over the wire rather
+// than a round-tripped struct that could mask a missing key.
+func contractPost(t *testing.T, url string, body map[string]any) []byte {
+	t.Helper()
+
+	reqBytes, err := json.Marshal(body)
+	if err != nil {
+		t.Fatalf("failed to marshal request: %v", err)
+	}
+
+	resp, err := http.Post(url, "application/json", bytes.NewReader(reqBytes))
+	if err != nil {
+		t.Fatalf("failed to call %s: %v", url, err)
+	}
+	defer resp.Body.Close()
+
+	respBytes, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatalf("failed to read response from %s: %v", url, err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("%s returned status %d: %s", url, resp.StatusCode, string(respBytes))
+	}
+	return respBytes
+}
+
+// contractStream POSTs body as JSON to url and returns each NDJSON line of
+// the streamed response as raw bytes.
+func contractStream(t *testing.T, url string, body map[string]any) [][]byte {
+	t.Helper()
+
+	reqBytes, err := json.Marshal(body)
+	if err != nil {
+		t.Fatalf("failed to marshal request: %v", err)
+	}
+
+	resp, err := http.Post(url, "application/json", bytes.NewReader(reqBytes))
+	if err != nil {
+		t.Fatalf("failed to call %s: %v", url, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		respBytes, _ := io.ReadAll(resp.Body)
+		t.Fatalf("%s returned status %d: %s", url, resp.StatusCode, string(respBytes))
+	}
+
+	var lines [][]byte
+	reader := bufio.NewReader(resp.Body)
+	for {
+		line, err := reader.ReadBytes('\n')
+		line = bytes.TrimSpace(line)
+		if len(line) > 0 {
+			lines = append(lines, line)
+		}
+		if err != nil {
+			if err == io.EOF {
+				break
+			}
+			t.Fatalf("failed to read stream from %s: %v", url, err)
+		}
+	}
+
+	if len(lines) < 2 {
+		t.Fatalf("expected at least a first and terminal chunk from %s, got %d", url, len(lines))
+	}
+	return lines
+}
+
+// newContractPNG returns a tiny solid-color PNG for the chat_with_image
+// case, mirroring server's own newTestPNG helper.
+func newContractPNG(t *testing.T) []byte {
+	t.Helper()
+
+	img := image.NewRGBA(image.Rect(0, 0, 8, 8))
+	fill := color.RGBA{G: 255, A: 255}
+	for y := 0; y < 8; y++ {
+		for x := 0; x < 8; x++ {
+			img.Set(x, y, fill)
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		t.Fatalf("failed to encode test image: %v", err)
+	}
+	return buf.Bytes()
+}
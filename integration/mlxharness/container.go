@@ -0,0 +1,88 @@
+package mlxharness
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/testcontainers/testcontainers-go"
+	"github.com/testcontainers/testcontainers-go/wait"
+
+	"github.com/ollama/ollama/llm"
+)
+
+// ollmlxContainerPort is the port ollmlx's server listens on inside the
+// container, regardless of which host port it ends up mapped to.
+const ollmlxContainerPort = "11434/tcp"
+
+// newContainerHarness launches image (an ollmlx image, mirroring
+// testcontainers-go's own ollama module) with a temporary models directory
+// bind-mounted in and seeded with a fake model, so tests get the same
+// isolation NewHarness's subprocess path does but without relying on any
+// `ollmlx` binary on the host.
+func newContainerHarness(image, modelRef string) (*Harness, error) {
+	modelsDir, err := os.MkdirTemp("", "ollmlx-mlxharness-models-*")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create temp models dir: %w", err)
+	}
+	cleanupModelsDir := func() { os.RemoveAll(modelsDir) }
+
+	if _, err := writeFakeMLXModel(modelsDir, modelRef); err != nil {
+		cleanupModelsDir()
+		return nil, fmt.Errorf("failed to write fake MLX model: %w", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
+	defer cancel()
+
+	req := testcontainers.ContainerRequest{
+		Image:        image,
+		ExposedPorts: []string{ollmlxContainerPort},
+		Env:          map[string]string{"OLLAMA_MODELS": "/models"},
+		Mounts: testcontainers.ContainerMounts{
+			testcontainers.BindMount(modelsDir, "/models"),
+		},
+		WaitingFor: wait.ForHTTP("/api/version").
+			WithPort(ollmlxContainerPort).
+			WithStartupTimeout(60 * time.Second),
+	}
+
+	container, err := testcontainers.GenericContainer(ctx, testcontainers.GenericContainerRequest{
+		ContainerRequest: req,
+		Started:          true,
+	})
+	if err != nil {
+		cleanupModelsDir()
+		return nil, fmt.Errorf("failed to start ollmlx container: %w", err)
+	}
+
+	host, err := container.Host(ctx)
+	if err != nil {
+		container.Terminate(ctx)
+		cleanupModelsDir()
+		return nil, fmt.Errorf("failed to resolve container host: %w", err)
+	}
+	mappedPort, err := container.MappedPort(ctx, ollmlxContainerPort)
+	if err != nil {
+		container.Terminate(ctx)
+		cleanupModelsDir()
+		return nil, fmt.Errorf("failed to resolve container port: %w", err)
+	}
+	baseURL := fmt.Sprintf("http://%s:%s", host, mappedPort.Port())
+
+	// MLXModelManager always reads envconfig.Models(); point the test
+	// process at the same models dir the container has mounted so
+	// assertions against on-disk state (MLXManager()) see what's inside.
+	os.Setenv("OLLAMA_MODELS", modelsDir)
+	manager := llm.NewMLXModelManager()
+
+	teardown := func() {
+		termCtx, termCancel := context.WithTimeout(context.Background(), 30*time.Second)
+		defer termCancel()
+		container.Terminate(termCtx)
+		cleanupModelsDir()
+	}
+
+	return &Harness{baseURL: baseURL, manager: manager, cleanup: teardown}, nil
+}
@@ -0,0 +1,103 @@
+package mlxharness
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/ollama/ollama/llm"
+)
+
+// fixture.go fakes a tiny-but-real MLX model directly on disk: a config.json,
+// tokenizer_config.json, and a single-tensor model.safetensors with a valid
+// header (see llm.MLXModelManager.ParameterCount). Writing it directly,
+// instead of pulling a real gallery model over the network, is what keeps
+// Harness hermetic.
+
+// fakeHiddenSize is the config.json "hidden_size" writeFakeMLXModel uses;
+// it also sizes the single tensor in model.safetensors, so the two stay
+// consistent with each other.
+const fakeHiddenSize = 4
+
+// WriteFakeModel writes a minimal MLX model for modelRef directly into
+// manager's models directory, the same way New seeds the harness's own
+// compatModel, so tests that need a second on-disk model (e.g. one a
+// control-plane reconciler should evict) don't have to pull one over the
+// network. It returns the model's local path.
+func WriteFakeModel(manager *llm.MLXModelManager, modelRef string) (string, error) {
+	return writeFakeMLXModel(manager.GetModelsDir(), modelRef)
+}
+
+// writeFakeMLXModel writes a minimal MLX model for modelRef under modelsDir,
+// in the same flat <org>_<repo> layout MLXModelManager.GetModelPath expects,
+// and returns its path.
+func writeFakeMLXModel(modelsDir, modelRef string) (string, error) {
+	localName := strings.ReplaceAll(modelRef, "/", "_")
+	modelPath := filepath.Join(modelsDir, localName)
+
+	if err := os.MkdirAll(modelPath, 0755); err != nil {
+		return "", fmt.Errorf("failed to create fake model dir: %w", err)
+	}
+
+	config := map[string]any{
+		"architectures": []string{"FakeMLXForCausalLM"},
+		"hidden_size":   fakeHiddenSize,
+		"model_type":    "fake-mlx",
+	}
+	if err := writeJSONFile(filepath.Join(modelPath, "config.json"), config); err != nil {
+		return "", err
+	}
+
+	tokenizerConfig := map[string]any{"tokenizer_class": "PreTrainedTokenizerFast"}
+	if err := writeJSONFile(filepath.Join(modelPath, "tokenizer_config.json"), tokenizerConfig); err != nil {
+		return "", err
+	}
+
+	if err := writeFakeSafetensors(filepath.Join(modelPath, "model.safetensors")); err != nil {
+		return "", err
+	}
+
+	return modelPath, nil
+}
+
+func writeJSONFile(path string, v any) error {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal %s: %w", filepath.Base(path), err)
+	}
+	return os.WriteFile(path, data, 0644)
+}
+
+// writeFakeSafetensors writes a single-tensor safetensors file: an 8-byte
+// little-endian header length, the header JSON itself, then that many
+// zeroed float32s as tensor data. Shaped [fakeHiddenSize, fakeHiddenSize],
+// so ParameterCount reports fakeHiddenSize*fakeHiddenSize parameters.
+func writeFakeSafetensors(path string) error {
+	const elements = fakeHiddenSize * fakeHiddenSize
+	data := make([]byte, elements*4)
+
+	header := map[string]any{
+		"embed.weight": map[string]any{
+			"dtype":        "F32",
+			"shape":        []int{fakeHiddenSize, fakeHiddenSize},
+			"data_offsets": []int{0, len(data)},
+		},
+	}
+	headerJSON, err := json.Marshal(header)
+	if err != nil {
+		return fmt.Errorf("failed to marshal safetensors header: %w", err)
+	}
+
+	var buf bytes.Buffer
+	if err := binary.Write(&buf, binary.LittleEndian, uint64(len(headerJSON))); err != nil {
+		return err
+	}
+	buf.Write(headerJSON)
+	buf.Write(data)
+
+	return os.WriteFile(path, buf.Bytes(), 0644)
+}
@@ -0,0 +1,233 @@
+// Package mlxharness spins up a hermetic ollmlx server for MLX integration
+// tests: a temporary OLLAMA_MODELS directory seeded with a small fake model
+// (see fixture.go), and either a host `ollmlx serve` subprocess backed by a
+// stub MLXBackend (see stubbackend/) or, when MLX_TEST_IMAGE is set, a real
+// ollmlx image running in a container. Tests that used to t.Skip whenever no
+// developer happened to have a server already running with the right model
+// pulled can instead call New and get one unconditionally.
+package mlxharness
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/ollama/ollama/llm"
+)
+
+// mlxTestImageEnv, when set, makes New launch the server inside that
+// container image instead of as a host subprocess - the same convention
+// testcontainers-go's own ollama module uses for its OLLAMA_TEST_IMAGE, so
+// CI can run these tests fully isolated from the runner's host state.
+const mlxTestImageEnv = "MLX_TEST_IMAGE"
+
+// Harness is a running ollmlx server isolated to a temporary OLLAMA_MODELS
+// directory, with one fake MLX model already cached in it (in container
+// mode too).
+type Harness struct {
+	baseURL string
+	manager *llm.MLXModelManager
+	cleanup func()
+
+	// crashBackend, when set, kills the running MLX backend subprocess out
+	// from under ollmlx serve, for tests that assert on crash recovery.
+	// It's nil for container harnesses, where the backend isn't a process
+	// the test can reach directly.
+	crashBackend func() error
+}
+
+// BaseURL returns the harness server's "http://host:port" root.
+func (h *Harness) BaseURL() string { return h.baseURL }
+
+// MLXManager returns an MLXModelManager pointed at the harness's temporary
+// models directory, for assertions against on-disk model state.
+func (h *Harness) MLXManager() *llm.MLXModelManager { return h.manager }
+
+// CrashBackend kills the harness's MLX backend subprocess without going
+// through ollmlx serve's own shutdown path, simulating the backend dying on
+// its own (OOM, segfault, etc.) so tests can assert the server surfaces a
+// clean error for any request in flight and recovers on the next one. Not
+// supported against a container harness (MLX_TEST_IMAGE); callers should
+// skip such tests when it returns an error.
+func (h *Harness) CrashBackend() error {
+	if h.crashBackend == nil {
+		return fmt.Errorf("mlxharness: CrashBackend is not supported for this harness")
+	}
+	return h.crashBackend()
+}
+
+// Cleanup tears down the server (or container) and removes its temporary
+// state. New registers this with t.Cleanup already; call it directly only
+// if you built a Harness with NewHarness instead (e.g. from TestMain, which
+// has no *testing.T to register against).
+func (h *Harness) Cleanup() { h.cleanup() }
+
+// New builds a Harness for the duration of t via NewHarness, failing or
+// skipping t on setup problems, and registers Cleanup with t.Cleanup.
+func New(t *testing.T, modelRef string) *Harness {
+	t.Helper()
+
+	h, err := NewHarness(modelRef)
+	if err != nil {
+		if err == errOllmlxNotFound {
+			t.Skipf("mlxharness: %v", err)
+		}
+		t.Fatalf("mlxharness: %v", err)
+	}
+
+	t.Cleanup(h.Cleanup)
+	return h
+}
+
+// errOllmlxNotFound is returned by newProcessHarness when there's no
+// `ollmlx` binary on PATH to exec - a reasonable thing to skip on rather
+// than fail, since not every environment running `go test` also has the
+// CLI built.
+var errOllmlxNotFound = fmt.Errorf("no ollmlx binary on PATH")
+
+// NewHarness builds a Harness without a *testing.T, for callers like
+// TestMain that want to set one up once for a whole package and tear it
+// down after m.Run returns.
+func NewHarness(modelRef string) (*Harness, error) {
+	if image := os.Getenv(mlxTestImageEnv); image != "" {
+		return newContainerHarness(image, modelRef)
+	}
+	return newProcessHarness(modelRef)
+}
+
+// newProcessHarness seeds a temporary OLLAMA_MODELS dir with a fake model,
+// builds the stub MLXBackend (stubbackend/), and execs `ollmlx serve`
+// pointed at both via OLLAMA_MODELS and OLLMLX_MLX_BACKEND_CMD.
+func newProcessHarness(modelRef string) (*Harness, error) {
+	bin, err := exec.LookPath("ollmlx")
+	if err != nil {
+		return nil, errOllmlxNotFound
+	}
+
+	modelsDir, err := os.MkdirTemp("", "ollmlx-mlxharness-models-*")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create temp models dir: %w", err)
+	}
+	cleanupModelsDir := func() { os.RemoveAll(modelsDir) }
+
+	if _, err := writeFakeMLXModel(modelsDir, modelRef); err != nil {
+		cleanupModelsDir()
+		return nil, fmt.Errorf("failed to write fake MLX model: %w", err)
+	}
+
+	stubDir, err := os.MkdirTemp("", "ollmlx-mlxharness-stub-*")
+	if err != nil {
+		cleanupModelsDir()
+		return nil, fmt.Errorf("failed to create temp stub dir: %w", err)
+	}
+	stubPath := filepath.Join(stubDir, "mlx-stub-backend")
+	if err := buildStubBackend(stubPath); err != nil {
+		cleanupModelsDir()
+		os.RemoveAll(stubDir)
+		return nil, err
+	}
+
+	port, err := freePort()
+	if err != nil {
+		cleanupModelsDir()
+		os.RemoveAll(stubDir)
+		return nil, fmt.Errorf("failed to find a free port: %w", err)
+	}
+	baseURL := fmt.Sprintf("http://127.0.0.1:%d", port)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cmd := exec.CommandContext(ctx, bin, "serve")
+	cmd.Env = append(os.Environ(),
+		"OLLAMA_MODELS="+modelsDir,
+		fmt.Sprintf("OLLAMA_HOST=127.0.0.1:%d", port),
+		"OLLMLX_MLX_BACKEND_CMD="+stubPath,
+	)
+	cmd.Stdout = os.Stderr
+	cmd.Stderr = os.Stderr
+
+	teardown := func() {
+		cancel()
+		cmd.Wait()
+		cleanupModelsDir()
+		os.RemoveAll(stubDir)
+	}
+
+	if err := cmd.Start(); err != nil {
+		cancel()
+		cleanupModelsDir()
+		os.RemoveAll(stubDir)
+		return nil, fmt.Errorf("failed to start ollmlx serve: %w", err)
+	}
+
+	if err := waitForHealthy(baseURL, 10*time.Second); err != nil {
+		teardown()
+		return nil, fmt.Errorf("ollmlx serve never became healthy: %w", err)
+	}
+
+	// MLXModelManager always reads envconfig.Models(), so point the whole
+	// test process at modelsDir too. Safe here because each test binary
+	// run only ever builds one Harness (see compatibility_test.go's
+	// package-level TestMain setup) and the process exits once tests do.
+	os.Setenv("OLLAMA_MODELS", modelsDir)
+	manager := llm.NewMLXModelManager()
+
+	crashBackend := func() error {
+		return exec.Command("pkill", "-f", stubPath).Run()
+	}
+
+	return &Harness{baseURL: baseURL, manager: manager, cleanup: teardown, crashBackend: crashBackend}, nil
+}
+
+// buildStubBackend compiles stubbackend/ into outPath, as a plain `go
+// build` invocation the same way `go test` itself would have been invoked -
+// cheap relative to starting a whole Python/MLX subprocess, and means the
+// harness doesn't need a prebuilt binary checked in anywhere.
+func buildStubBackend(outPath string) error {
+	cmd := exec.Command("go", "build", "-o", outPath, "github.com/ollama/ollama/integration/mlxharness/stubbackend")
+	cmd.Stdout = os.Stderr
+	cmd.Stderr = os.Stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("failed to build stub MLX backend: %w", err)
+	}
+	return nil
+}
+
+// freePort asks the OS for an ephemeral port and releases it immediately,
+// so the caller can pass it to a subprocess that binds its own listener.
+// Like any such trick there's a narrow window where something else grabs
+// the port first; acceptable for test harnesses, not for production.
+func freePort() (int, error) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return 0, err
+	}
+	defer listener.Close()
+	return listener.Addr().(*net.TCPAddr).Port, nil
+}
+
+// waitForHealthy polls baseURL's /api/version until it answers 200 or
+// timeout elapses.
+func waitForHealthy(baseURL string, timeout time.Duration) error {
+	deadline := time.Now().Add(timeout)
+	var lastErr error
+	for time.Now().Before(deadline) {
+		resp, err := http.Get(baseURL + "/api/version")
+		if err == nil {
+			resp.Body.Close()
+			if resp.StatusCode == http.StatusOK {
+				return nil
+			}
+			lastErr = fmt.Errorf("unexpected status %d", resp.StatusCode)
+		} else {
+			lastErr = err
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+	return lastErr
+}
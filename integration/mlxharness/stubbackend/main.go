@@ -0,0 +1,100 @@
+// Command stubbackend is a fake MLXBackend gRPC server used only by
+// mlxharness: it speaks the same contract (proto/mlx/v1/mlx.proto) as the
+// real Python MLX backend, but returns a canned completion instead of
+// running actual inference, so integration tests can exercise ollmlx's
+// server/runner plumbing without the Python/MLX stack installed.
+package main
+
+import (
+	"context"
+	"flag"
+	"log"
+	"net"
+	"os"
+
+	"google.golang.org/grpc"
+
+	"github.com/ollama/ollama/runner/mlxrunner/mlxpb"
+)
+
+type stubServer struct {
+	mlxpb.UnimplementedMLXBackendServer
+
+	modelPath string
+}
+
+func (s *stubServer) Load(ctx context.Context, req *mlxpb.LoadRequest) (*mlxpb.LoadResponse, error) {
+	if _, err := os.Stat(req.ModelPath); err != nil {
+		return &mlxpb.LoadResponse{Ok: false, Error: err.Error()}, nil
+	}
+	s.modelPath = req.ModelPath
+	return &mlxpb.LoadResponse{Ok: true}, nil
+}
+
+func (s *stubServer) PredictStream(req *mlxpb.PredictRequest, stream mlxpb.MLXBackend_PredictStreamServer) error {
+	tokens := []string{"stub", " ", "response"}
+	for i, tok := range tokens {
+		if err := stream.Send(&mlxpb.PredictChunk{
+			Token:            tok,
+			Done:             false,
+			PromptTokens:     int32(len(req.Prompt)),
+			CompletionTokens: int32(i + 1),
+		}); err != nil {
+			return err
+		}
+	}
+	return stream.Send(&mlxpb.PredictChunk{
+		Done:             true,
+		DoneReason:       "stop",
+		PromptTokens:     int32(len(req.Prompt)),
+		CompletionTokens: int32(len(tokens)),
+	})
+}
+
+func (s *stubServer) Embed(ctx context.Context, req *mlxpb.EmbedRequest) (*mlxpb.EmbedResponse, error) {
+	vectors := make([]*mlxpb.FloatVector, len(req.Input))
+	for i := range req.Input {
+		vectors[i] = &mlxpb.FloatVector{Values: []float32{0, 0, 0, 0}}
+	}
+	return &mlxpb.EmbedResponse{Embeddings: vectors}, nil
+}
+
+func (s *stubServer) Tokenize(ctx context.Context, req *mlxpb.TokenizeRequest) (*mlxpb.TokenizeResponse, error) {
+	tokens := make([]int32, len(req.Text))
+	for i := range req.Text {
+		tokens[i] = int32(i)
+	}
+	return &mlxpb.TokenizeResponse{Tokens: tokens}, nil
+}
+
+func (s *stubServer) Health(ctx context.Context, req *mlxpb.HealthRequest) (*mlxpb.HealthResponse, error) {
+	return &mlxpb.HealthResponse{Ok: true}, nil
+}
+
+func (s *stubServer) Status(ctx context.Context, req *mlxpb.StatusRequest) (*mlxpb.StatusResponse, error) {
+	return &mlxpb.StatusResponse{Loaded: s.modelPath != "", ModelPath: s.modelPath}, nil
+}
+
+func main() {
+	sockPath := flag.String("socket", "", "unix socket path to serve MLXBackend on")
+	flag.Parse()
+
+	if *sockPath == "" {
+		log.Fatal("stubbackend: --socket is required")
+	}
+	os.Remove(*sockPath)
+
+	listener, err := net.Listen("unix", *sockPath)
+	if err != nil {
+		log.Fatalf("stubbackend: failed to listen on %s: %v", *sockPath, err)
+	}
+
+	// Must match the codec server.go's dialUnix forces on the client side;
+	// see mlxpb.Codec's doc comment for why the default "proto" codec can't
+	// be used here.
+	grpcServer := grpc.NewServer(grpc.ForceServerCodec(mlxpb.Codec{}))
+	mlxpb.RegisterMLXBackendServer(grpcServer, &stubServer{})
+	if err := grpcServer.Serve(listener); err != nil {
+		log.Fatalf("stubbackend: serve failed: %v", err)
+	}
+}
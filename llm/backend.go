@@ -0,0 +1,81 @@
+package llm
+
+import "context"
+
+// backend.go generalizes the hard-coded MLX-only dispatch that used to be
+// spread across server/routes_mlx.go (IsMLXModelReference's
+// strings.HasPrefix("mlx-community/") check, direct llm.NewMLXModelManager()
+// calls) into a small Backend interface any runtime can implement, plus a
+// BackendRegistry that routes a model reference to the first registered
+// Backend willing to claim it. MLXBackend (backend_mlx.go) is the first
+// built-in; ExternalBackend (backend_external.go) lets third-party runtimes
+// like llama.cpp-metal or vLLM register themselves without recompiling
+// ollmlx, by dropping a config file in ~/.ollmlx/backends.
+
+// BackendToken is one piece of a streamed completion, the backend-agnostic
+// counterpart of mlxrunner.Token.
+type BackendToken struct {
+	Text             string
+	Done             bool
+	DoneReason       string
+	Err              error
+	PromptTokens     int
+	CompletionTokens int
+}
+
+// Backend is anything that can serve models under its own naming
+// convention: claim a model reference, manage its local cache of pulled
+// models, and run completions against a loaded model.
+type Backend interface {
+	// Name identifies the backend in logs and error messages.
+	Name() string
+	// Detect reports whether modelRef is one this backend can serve.
+	Detect(modelRef string) bool
+	// Pull downloads modelRef, reporting progress through fn.
+	Pull(ctx context.Context, modelRef string, fn func(status string, completed, total int64)) error
+	// List returns every model this backend has cached locally.
+	List() ([]MLXModelInfo, error)
+	// Show returns metadata for a single cached model.
+	Show(modelRef string) (MLXModelInfo, error)
+	// Delete removes a cached model.
+	Delete(modelRef string) error
+	// Generate streams a completion for prompt against modelRef, loading it
+	// first if it isn't already warm.
+	Generate(ctx context.Context, modelRef, prompt string, maxTokens int, temperature, topP float32, stop []string, grammar string, images [][]byte) (<-chan BackendToken, error)
+}
+
+// BackendRegistry routes model references to the Backend that claims them,
+// trying each registered Backend's Detect in registration order: built-ins
+// first, then external backends in the order their config files were
+// loaded. Register is expected to run during startup only; Detect and All
+// are safe to call concurrently with each other once registration is done.
+type BackendRegistry struct {
+	backends []Backend
+}
+
+// NewBackendRegistry constructs an empty registry; callers register
+// backends with Register.
+func NewBackendRegistry() *BackendRegistry {
+	return &BackendRegistry{}
+}
+
+// Register adds b to the registry, after every Backend already registered.
+func (r *BackendRegistry) Register(b Backend) {
+	r.backends = append(r.backends, b)
+}
+
+// Detect returns the first registered Backend whose Detect matches
+// modelRef.
+func (r *BackendRegistry) Detect(modelRef string) (Backend, bool) {
+	for _, b := range r.backends {
+		if b.Detect(modelRef) {
+			return b, true
+		}
+	}
+	return nil, false
+}
+
+// All returns every registered backend, in registration order.
+func (r *BackendRegistry) All() []Backend {
+	return r.backends
+}
@@ -0,0 +1,213 @@
+package llm
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/ollama/ollama/runner/mlxrunner"
+)
+
+// ExternalBackendConfig is the on-disk YAML shape read from
+// ~/.ollmlx/backends/*.yaml: enough to launch a third-party runtime that
+// speaks the same gRPC contract mlxrunner.Server does, and to know which
+// model references it should claim.
+type ExternalBackendConfig struct {
+	Name    string   `yaml:"name"`
+	Command string   `yaml:"command"`
+	Args    []string `yaml:"args,omitempty"`
+	// Match lists name substrings this backend claims, e.g. ["vllm/", "-vllm"].
+	// A model reference matches if it contains any entry.
+	Match []string `yaml:"match"`
+}
+
+// ExternalBackendsDir returns where ollmlx looks for third-party backend
+// configs, overridable via OLLMLX_BACKENDS_DIR for tests and non-standard
+// layouts.
+func ExternalBackendsDir() (string, error) {
+	if dir := os.Getenv("OLLMLX_BACKENDS_DIR"); dir != "" {
+		return dir, nil
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(home, ".ollmlx", "backends"), nil
+}
+
+// LoadExternalBackendConfigs reads every *.yaml file in dir, in
+// lexicographic filename order so operators can control Detect precedence
+// by naming their files (e.g. "10-vllm.yaml" before "20-llamacpp.yaml"). A
+// missing dir isn't an error: it just means no external backends are
+// configured.
+func LoadExternalBackendConfigs(dir string) ([]ExternalBackendConfig, error) {
+	entries, err := os.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read backends dir %s: %w", dir, err)
+	}
+
+	var names []string
+	for _, e := range entries {
+		if !e.IsDir() && strings.HasSuffix(e.Name(), ".yaml") {
+			names = append(names, e.Name())
+		}
+	}
+	sort.Strings(names)
+
+	var configs []ExternalBackendConfig
+	for _, name := range names {
+		data, err := os.ReadFile(filepath.Join(dir, name))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", name, err)
+		}
+		var cfg ExternalBackendConfig
+		if err := yaml.Unmarshal(data, &cfg); err != nil {
+			return nil, fmt.Errorf("failed to parse %s: %w", name, err)
+		}
+		if cfg.Name == "" {
+			cfg.Name = strings.TrimSuffix(name, ".yaml")
+		}
+		configs = append(configs, cfg)
+	}
+	return configs, nil
+}
+
+// ExternalBackend supervises a third-party runtime subprocess speaking the
+// same gRPC contract mlxrunner.Server does (proto/mlx/v1/mlx.proto): Load,
+// Predict, Embed, Tokenize, Health, Status. Any runtime that implements that
+// contract — llama.cpp-metal, vLLM, or anything else — can be auto-loaded
+// this way, independent of ollmlx's own Python MLX backend. Pull/List/Show/
+// Delete are intentionally unsupported: external backends manage their own
+// model cache outside ollmlx's blob store.
+type ExternalBackend struct {
+	cfg ExternalBackendConfig
+
+	mu     sync.Mutex
+	runner *mlxrunner.Server
+	loaded string // modelRef currently loaded into runner, "" if none yet
+}
+
+// NewExternalBackend wraps cfg as a Backend. The subprocess isn't started
+// until Probe or Generate first needs it.
+func NewExternalBackend(cfg ExternalBackendConfig) *ExternalBackend {
+	return &ExternalBackend{cfg: cfg}
+}
+
+func (b *ExternalBackend) Name() string { return b.cfg.Name }
+
+// Detect reports whether modelRef contains any of cfg.Match's substrings.
+func (b *ExternalBackend) Detect(modelRef string) bool {
+	for _, m := range b.cfg.Match {
+		if m != "" && strings.Contains(modelRef, m) {
+			return true
+		}
+	}
+	return false
+}
+
+// Probe launches the backend subprocess (if not already running) and waits
+// for it to report healthy, so server startup can fail fast on a
+// misconfigured external backend instead of at the first request.
+func (b *ExternalBackend) Probe(ctx context.Context) error {
+	runner, err := b.ensureRunner(ctx)
+	if err != nil {
+		return err
+	}
+	return runner.Health(ctx)
+}
+
+// ensureRunner starts the backend subprocess on first use; later calls
+// reuse the same *mlxrunner.Server.
+func (b *ExternalBackend) ensureRunner(ctx context.Context) (*mlxrunner.Server, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.runner != nil {
+		return b.runner, nil
+	}
+
+	runner := mlxrunner.NewServerWithCommand("", b.cfg.Command, b.cfg.Args)
+	if err := runner.Start(ctx); err != nil {
+		return nil, fmt.Errorf("failed to start external backend %s: %w", b.cfg.Name, err)
+	}
+	b.runner = runner
+	return runner, nil
+}
+
+func (b *ExternalBackend) Pull(ctx context.Context, modelRef string, fn func(status string, completed, total int64)) error {
+	return fmt.Errorf("external backend %s does not support pulling models through ollmlx", b.cfg.Name)
+}
+
+func (b *ExternalBackend) List() ([]MLXModelInfo, error) {
+	return nil, fmt.Errorf("external backend %s does not expose a model list through ollmlx", b.cfg.Name)
+}
+
+func (b *ExternalBackend) Show(modelRef string) (MLXModelInfo, error) {
+	return MLXModelInfo{}, fmt.Errorf("external backend %s does not expose model info through ollmlx", b.cfg.Name)
+}
+
+func (b *ExternalBackend) Delete(modelRef string) error {
+	return fmt.Errorf("external backend %s does not support deleting models through ollmlx", b.cfg.Name)
+}
+
+// Generate loads modelRef into the backend (if it isn't already the loaded
+// model) and relays its token stream as backend-agnostic BackendTokens.
+func (b *ExternalBackend) Generate(ctx context.Context, modelRef, prompt string, maxTokens int, temperature, topP float32, stop []string, grammar string, images [][]byte) (<-chan BackendToken, error) {
+	runner, err := b.ensureRunner(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	b.mu.Lock()
+	needsLoad := b.loaded != modelRef
+	b.mu.Unlock()
+
+	if needsLoad {
+		if err := runner.Load(ctx, modelRef); err != nil {
+			return nil, fmt.Errorf("failed to load %s into external backend %s: %w", modelRef, b.cfg.Name, err)
+		}
+		b.mu.Lock()
+		b.loaded = modelRef
+		b.mu.Unlock()
+	}
+
+	tokens, err := runner.Predict(ctx, prompt, maxTokens, temperature, topP, stop, grammar, images)
+	if err != nil {
+		return nil, err
+	}
+
+	out := make(chan BackendToken)
+	go func() {
+		defer close(out)
+		for tok := range tokens {
+			out <- BackendToken{
+				Text:             tok.Text,
+				Done:             tok.Done,
+				DoneReason:       tok.DoneReason,
+				Err:              tok.Err,
+				PromptTokens:     tok.PromptTokens,
+				CompletionTokens: tok.CompletionTokens,
+			}
+		}
+	}()
+	return out, nil
+}
+
+// Close terminates the backend subprocess, if one was started.
+func (b *ExternalBackend) Close() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.runner == nil {
+		return nil
+	}
+	return b.runner.Close()
+}
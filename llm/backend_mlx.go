@@ -0,0 +1,87 @@
+package llm
+
+import (
+	"context"
+	"strings"
+)
+
+// MLXBackend adapts MLXModelManager and a warm-runner MLXRunnerPool onto the
+// Backend interface, as the BackendRegistry's first built-in backend.
+type MLXBackend struct {
+	manager *MLXModelManager
+	pool    *MLXRunnerPool
+}
+
+// NewMLXBackend wraps manager and pool as a Backend.
+func NewMLXBackend(manager *MLXModelManager, pool *MLXRunnerPool) *MLXBackend {
+	return &MLXBackend{manager: manager, pool: pool}
+}
+
+func (b *MLXBackend) Name() string { return "mlx" }
+
+// Detect matches the same references server.IsMLXModelReference always
+// has: an explicit mlx-community/ org, "-mlx" anywhere in the name
+// (case-insensitive), or a model already cached locally under this backend.
+func (b *MLXBackend) Detect(modelRef string) bool {
+	if strings.HasPrefix(modelRef, "mlx-community/") {
+		return true
+	}
+	if strings.Contains(strings.ToLower(modelRef), "-mlx") {
+		return true
+	}
+	return b.manager.ModelExists(modelRef)
+}
+
+func (b *MLXBackend) Pull(ctx context.Context, modelRef string, fn func(status string, completed, total int64)) error {
+	return b.manager.DownloadMLXModel(ctx, modelRef, func(p MLXDownloadProgress) {
+		fn(p.Status, p.Completed, p.Total)
+	})
+}
+
+func (b *MLXBackend) List() ([]MLXModelInfo, error) {
+	return b.manager.ListModels()
+}
+
+func (b *MLXBackend) Show(modelRef string) (MLXModelInfo, error) {
+	return b.manager.GetModelInfo(strings.ReplaceAll(modelRef, "/", "_"))
+}
+
+func (b *MLXBackend) Delete(modelRef string) error {
+	return b.manager.DeleteModel(modelRef)
+}
+
+// Generate acquires a warm runner for modelRef from the pool (starting and
+// loading one if none is running) and relays its token stream as
+// backend-agnostic BackendTokens.
+func (b *MLXBackend) Generate(ctx context.Context, modelRef, prompt string, maxTokens int, temperature, topP float32, stop []string, grammar string, images [][]byte) (<-chan BackendToken, error) {
+	localName := strings.ReplaceAll(modelRef, "/", "_")
+	modelPath := b.manager.GetModelPath(localName)
+
+	handle, err := b.pool.Acquire(ctx, modelPath)
+	if err != nil {
+		return nil, err
+	}
+
+	tokens, err := handle.Server().Predict(ctx, prompt, maxTokens, temperature, topP, stop, grammar, images)
+	if err != nil {
+		handle.Release()
+		return nil, err
+	}
+
+	out := make(chan BackendToken)
+	go func() {
+		defer close(out)
+		defer handle.Release()
+		for tok := range tokens {
+			out <- BackendToken{
+				Text:             tok.Text,
+				Done:             tok.Done,
+				DoneReason:       tok.DoneReason,
+				Err:              tok.Err,
+				PromptTokens:     tok.PromptTokens,
+				CompletionTokens: tok.CompletionTokens,
+			}
+		}
+	}()
+	return out, nil
+}
@@ -0,0 +1,270 @@
+package llm
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+const manifestFileName = "manifest.json"
+
+// blobDescriptor mirrors an OCI/Docker Registry v2 schema-2 layer
+// descriptor: enough to reconstruct a model's file tree from the blob store
+// without walking the directory or re-hashing its contents.
+type blobDescriptor struct {
+	Filename  string `json:"filename"`
+	MediaType string `json:"mediaType"`
+	Size      int64  `json:"size"`
+	Digest    string `json:"digest"`
+}
+
+// modelManifest is the per-model equivalent of a registry manifest: an
+// ordered list of layer descriptors, one per downloaded file.
+type modelManifest struct {
+	SchemaVersion int              `json:"schemaVersion"`
+	Layers        []blobDescriptor `json:"layers"`
+}
+
+func mediaTypeFor(filename string) string {
+	switch {
+	case strings.HasSuffix(filename, ".safetensors"):
+		return "application/vnd.mlx.safetensors"
+	case strings.HasSuffix(filename, ".npz"):
+		return "application/vnd.mlx.npz"
+	case strings.HasSuffix(filename, ".json"):
+		return "application/json"
+	default:
+		return "application/octet-stream"
+	}
+}
+
+// blobsRoot returns <modelsDir>/mlx-blobs/sha256, mirroring the OCI
+// "sha256/<hex>" blob layout.
+func (m *MLXModelManager) blobsRoot() string {
+	return filepath.Join(m.modelsDir, "mlx-blobs", "sha256")
+}
+
+// blobsTmpDir is where in-flight downloads land before their digest is
+// known, so a partially written blob is never mistaken for a complete one.
+func (m *MLXModelManager) blobsTmpDir() string {
+	return filepath.Join(m.modelsDir, "mlx-blobs", "tmp")
+}
+
+// blobsTmpDirFor is blobsTmpDir namespaced by the destination model's own
+// path, so two pulls in flight at once for different models never land a
+// same-named file (config.json, tokenizer.json, a shard that happens to
+// share its name across unrelated HF repos, ...) in the same tmp path.
+// Keying by modelPath rather than a random per-call suffix is deliberate:
+// a second pull of the *same* model still resumes the first one's
+// partially-written ".part" file instead of starting over.
+func (m *MLXModelManager) blobsTmpDirFor(modelPath string) string {
+	return filepath.Join(m.blobsTmpDir(), filepath.Base(modelPath))
+}
+
+func (m *MLXModelManager) blobPath(digest string) string {
+	return filepath.Join(m.blobsRoot(), strings.TrimPrefix(digest, "sha256:"))
+}
+
+func manifestPath(modelPath string) string {
+	return filepath.Join(modelPath, manifestFileName)
+}
+
+func readManifest(modelPath string) (*modelManifest, error) {
+	data, err := os.ReadFile(manifestPath(modelPath))
+	if err != nil {
+		return nil, err
+	}
+	var man modelManifest
+	if err := json.Unmarshal(data, &man); err != nil {
+		return nil, err
+	}
+	return &man, nil
+}
+
+func writeManifest(modelPath string, man *modelManifest) error {
+	data, err := json.MarshalIndent(man, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(manifestPath(modelPath), data, 0644)
+}
+
+// digestFile streams path through sha256 and returns "sha256:<hex>".
+func digestFile(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return "sha256:" + hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// remoteBlobDigest asks the HF resolve endpoint for a file's content digest
+// without downloading it, via the X-Linked-ETag header HF sets on
+// LFS-tracked files (a sha256 hex digest), or the plain ETag header when
+// X-Linked-ETag is absent. Returns "" when neither holds a sha256 hex digest,
+// in which case the caller falls back to downloading and hashing the file.
+func remoteBlobDigest(ctx context.Context, client *http.Client, fileURL, token string) string {
+	req, err := http.NewRequestWithContext(ctx, http.MethodHead, fileURL, nil)
+	if err != nil {
+		return ""
+	}
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return ""
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, resp.Body)
+
+	etag := resp.Header.Get("X-Linked-ETag")
+	if etag == "" {
+		etag = resp.Header.Get("ETag")
+	}
+	etag = strings.TrimPrefix(strings.Trim(etag, `"`), "W/")
+
+	if len(etag) == 64 {
+		if _, err := hex.DecodeString(etag); err == nil {
+			return "sha256:" + etag
+		}
+	}
+	return ""
+}
+
+// storeBlobFromFile moves src into the blob store keyed by its content
+// digest, returning the resulting descriptor. If a blob with the same
+// digest already exists (e.g. shared across two sibling models), src is
+// discarded and the existing blob is reused.
+func (m *MLXModelManager) storeBlobFromFile(src, filename string) (blobDescriptor, error) {
+	digest, err := digestFile(src)
+	if err != nil {
+		return blobDescriptor{}, err
+	}
+
+	if err := os.MkdirAll(m.blobsRoot(), 0755); err != nil {
+		return blobDescriptor{}, err
+	}
+
+	dst := m.blobPath(digest)
+	if _, err := os.Stat(dst); err == nil {
+		os.Remove(src)
+	} else if err := os.Rename(src, dst); err != nil {
+		return blobDescriptor{}, fmt.Errorf("failed to store blob for %s: %w", filename, err)
+	}
+
+	stat, err := os.Stat(dst)
+	if err != nil {
+		return blobDescriptor{}, err
+	}
+
+	return blobDescriptor{
+		Filename:  filename,
+		MediaType: mediaTypeFor(filename),
+		Size:      stat.Size(),
+		Digest:    digest,
+	}, nil
+}
+
+// linkBlobIntoModel exposes a blob at modelPath/<desc.Filename>. It prefers
+// a hardlink (cheap, survives the blob store living on the same filesystem)
+// and falls back to a symlink otherwise.
+func (m *MLXModelManager) linkBlobIntoModel(modelPath string, desc blobDescriptor) error {
+	dest := filepath.Join(modelPath, desc.Filename)
+	os.Remove(dest)
+
+	src := m.blobPath(desc.Digest)
+	if err := os.Link(src, dest); err == nil {
+		return nil
+	}
+	return os.Symlink(src, dest)
+}
+
+// blobRefCounts counts, across every manifest under modelsDir, how many
+// models reference each blob digest. DeleteModel's GC pass uses this to
+// decide which blobs are safe to remove.
+func (m *MLXModelManager) blobRefCounts() (map[string]int, error) {
+	counts := make(map[string]int)
+
+	entries, err := os.ReadDir(m.modelsDir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return counts, nil
+		}
+		return nil, err
+	}
+
+	for _, entry := range entries {
+		if !entry.IsDir() || internalDirs[entry.Name()] {
+			continue
+		}
+		man, err := readManifest(filepath.Join(m.modelsDir, entry.Name()))
+		if err != nil {
+			continue
+		}
+		for _, layer := range man.Layers {
+			counts[layer.Digest]++
+		}
+	}
+
+	return counts, nil
+}
+
+// gcUnreferencedBlobs removes every blob under blobsRoot whose digest has a
+// zero refcount in counts, along with that blob's "*.chunks.json" sidecar
+// (see sidecarPath) if it has one. A sidecar's own filename isn't a bare
+// digest, so its refcount is derived from the blob it describes rather than
+// looked up directly - otherwise it would never appear in counts, always
+// read as unreferenced, and get deleted on every GC pass regardless of
+// whether the blob it indexes is still in use.
+func (m *MLXModelManager) gcUnreferencedBlobs(counts map[string]int) error {
+	entries, err := os.ReadDir(m.blobsRoot())
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+
+	for _, entry := range entries {
+		name := entry.Name()
+		digest := "sha256:" + strings.TrimSuffix(name, ".chunks.json")
+		if counts[digest] == 0 {
+			os.Remove(filepath.Join(m.blobsRoot(), name))
+		}
+	}
+
+	return nil
+}
+
+// manifestSize and manifestDigest let callers that have already loaded a
+// manifest avoid re-walking the model directory for size/digest info.
+func manifestSize(man *modelManifest) int64 {
+	var total int64
+	for _, layer := range man.Layers {
+		total += layer.Size
+	}
+	return total
+}
+
+func manifestDigest(man *modelManifest) string {
+	h := sha256.New()
+	for _, layer := range man.Layers {
+		fmt.Fprintf(h, "%s:%d:%s\n", layer.Filename, layer.Size, layer.Digest)
+	}
+	return fmt.Sprintf("sha256:%x", h.Sum(nil))
+}
@@ -0,0 +1,222 @@
+package llm
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func newTestManager(t *testing.T) *MLXModelManager {
+	t.Helper()
+	return &MLXModelManager{modelsDir: t.TempDir()}
+}
+
+// TestDigestFile verifies digestFile returns the "sha256:<hex>" form of the
+// file's actual content hash, since every blob is keyed by exactly this
+// string.
+func TestDigestFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "f")
+	content := []byte("hello mlx blob store")
+	if err := os.WriteFile(path, content, 0644); err != nil {
+		t.Fatalf("failed to write test file: %v", err)
+	}
+
+	got, err := digestFile(path)
+	if err != nil {
+		t.Fatalf("digestFile failed: %v", err)
+	}
+
+	sum := sha256.Sum256(content)
+	want := "sha256:" + hex.EncodeToString(sum[:])
+	if got != want {
+		t.Errorf("digestFile = %q, want %q", got, want)
+	}
+}
+
+// TestStoreBlobFromFileDedup verifies that storing two files with identical
+// content lands them under the same digest-keyed blob path, and that the
+// second store discards its source rather than erroring on the existing
+// destination.
+func TestStoreBlobFromFileDedup(t *testing.T) {
+	m := newTestManager(t)
+	content := []byte("duplicate content shared by two sibling models")
+
+	src1 := filepath.Join(t.TempDir(), "config.json")
+	src2 := filepath.Join(t.TempDir(), "config.json")
+	for _, src := range []string{src1, src2} {
+		if err := os.WriteFile(src, content, 0644); err != nil {
+			t.Fatalf("failed to write %s: %v", src, err)
+		}
+	}
+
+	desc1, err := m.storeBlobFromFile(src1, "config.json")
+	if err != nil {
+		t.Fatalf("first storeBlobFromFile failed: %v", err)
+	}
+	desc2, err := m.storeBlobFromFile(src2, "config.json")
+	if err != nil {
+		t.Fatalf("second storeBlobFromFile failed: %v", err)
+	}
+
+	if desc1.Digest != desc2.Digest {
+		t.Fatalf("identical content produced different digests: %s vs %s", desc1.Digest, desc2.Digest)
+	}
+	if _, err := os.Stat(src1); !os.IsNotExist(err) {
+		t.Error("expected storeBlobFromFile to move (not copy) its source away")
+	}
+	if _, err := os.Stat(src2); !os.IsNotExist(err) {
+		t.Error("expected the second storeBlobFromFile's source to be discarded since the blob already existed")
+	}
+	if _, err := os.Stat(m.blobPath(desc1.Digest)); err != nil {
+		t.Errorf("expected a single blob at %s: %v", m.blobPath(desc1.Digest), err)
+	}
+}
+
+// TestLinkBlobIntoModel verifies the linked file at modelPath/filename
+// actually exposes the blob's content, whether hardlinked or (if the blob
+// store lives on a different filesystem) symlinked.
+func TestLinkBlobIntoModel(t *testing.T) {
+	m := newTestManager(t)
+	content := []byte("weights")
+
+	src := filepath.Join(t.TempDir(), "model.safetensors")
+	if err := os.WriteFile(src, content, 0644); err != nil {
+		t.Fatalf("failed to write source file: %v", err)
+	}
+	desc, err := m.storeBlobFromFile(src, "model.safetensors")
+	if err != nil {
+		t.Fatalf("storeBlobFromFile failed: %v", err)
+	}
+
+	modelPath := filepath.Join(m.modelsDir, "some_model")
+	if err := os.MkdirAll(modelPath, 0755); err != nil {
+		t.Fatalf("failed to create model dir: %v", err)
+	}
+	if err := m.linkBlobIntoModel(modelPath, desc); err != nil {
+		t.Fatalf("linkBlobIntoModel failed: %v", err)
+	}
+
+	got, err := os.ReadFile(filepath.Join(modelPath, "model.safetensors"))
+	if err != nil {
+		t.Fatalf("failed to read linked file: %v", err)
+	}
+	if string(got) != string(content) {
+		t.Errorf("linked file content = %q, want %q", got, content)
+	}
+
+	// Re-linking (e.g. a repeat DownloadMLXModel) must replace the old
+	// link rather than failing because the destination already exists.
+	if err := m.linkBlobIntoModel(modelPath, desc); err != nil {
+		t.Fatalf("re-linking over an existing link failed: %v", err)
+	}
+}
+
+// TestBlobRefCountsAndGC verifies blobRefCounts tallies a blob's references
+// across every model manifest, and that gcUnreferencedBlobs removes only
+// the blobs left at zero.
+func TestBlobRefCountsAndGC(t *testing.T) {
+	m := newTestManager(t)
+
+	writeBlob := func(content string) blobDescriptor {
+		src := filepath.Join(t.TempDir(), "f")
+		if err := os.WriteFile(src, []byte(content), 0644); err != nil {
+			t.Fatalf("failed to write blob source: %v", err)
+		}
+		desc, err := m.storeBlobFromFile(src, "f")
+		if err != nil {
+			t.Fatalf("storeBlobFromFile failed: %v", err)
+		}
+		return desc
+	}
+
+	shared := writeBlob("shared across two models")
+	orphan := writeBlob("referenced by nothing")
+
+	for _, name := range []string{"model_a", "model_b"} {
+		modelPath := filepath.Join(m.modelsDir, name)
+		if err := os.MkdirAll(modelPath, 0755); err != nil {
+			t.Fatalf("failed to create %s: %v", name, err)
+		}
+		if err := writeManifest(modelPath, &modelManifest{SchemaVersion: 1, Layers: []blobDescriptor{shared}}); err != nil {
+			t.Fatalf("failed to write manifest for %s: %v", name, err)
+		}
+	}
+
+	counts, err := m.blobRefCounts()
+	if err != nil {
+		t.Fatalf("blobRefCounts failed: %v", err)
+	}
+	if counts[shared.Digest] != 2 {
+		t.Errorf("shared blob refcount = %d, want 2", counts[shared.Digest])
+	}
+	if counts[orphan.Digest] != 0 {
+		t.Errorf("orphan blob refcount = %d, want 0", counts[orphan.Digest])
+	}
+
+	if err := m.gcUnreferencedBlobs(counts); err != nil {
+		t.Fatalf("gcUnreferencedBlobs failed: %v", err)
+	}
+
+	if _, err := os.Stat(m.blobPath(shared.Digest)); err != nil {
+		t.Errorf("expected referenced blob to survive GC: %v", err)
+	}
+	if _, err := os.Stat(m.blobPath(orphan.Digest)); !os.IsNotExist(err) {
+		t.Error("expected unreferenced blob to be removed by GC")
+	}
+}
+
+// TestGCUnreferencedBlobsHandlesChunkSidecars verifies gcUnreferencedBlobs
+// keys a "*.chunks.json" sidecar's fate to the blob digest it describes
+// rather than treating the sidecar's own filename as an always-zero-refcount
+// digest: a sidecar for a still-referenced blob must survive GC, while one
+// for an orphaned blob should be cleaned up alongside it.
+func TestGCUnreferencedBlobsHandlesChunkSidecars(t *testing.T) {
+	m := newTestManager(t)
+
+	writeBlob := func(content string) blobDescriptor {
+		src := filepath.Join(t.TempDir(), "f")
+		if err := os.WriteFile(src, []byte(content), 0644); err != nil {
+			t.Fatalf("failed to write blob source: %v", err)
+		}
+		desc, err := m.storeBlobFromFile(src, "f")
+		if err != nil {
+			t.Fatalf("storeBlobFromFile failed: %v", err)
+		}
+		return desc
+	}
+
+	referenced := writeBlob("kept.safetensors content")
+	orphan := writeBlob("orphaned.safetensors content")
+
+	for _, desc := range []blobDescriptor{referenced, orphan} {
+		sidecar := &chunkSidecar{Digest: desc.Digest, Size: desc.Size}
+		if err := writeSidecar(sidecarPath(m.blobPath(desc.Digest)), sidecar); err != nil {
+			t.Fatalf("failed to write sidecar for %s: %v", desc.Digest, err)
+		}
+	}
+
+	modelPath := filepath.Join(m.modelsDir, "model_a")
+	if err := os.MkdirAll(modelPath, 0755); err != nil {
+		t.Fatalf("failed to create model dir: %v", err)
+	}
+	if err := writeManifest(modelPath, &modelManifest{SchemaVersion: 1, Layers: []blobDescriptor{referenced}}); err != nil {
+		t.Fatalf("failed to write manifest: %v", err)
+	}
+
+	counts, err := m.blobRefCounts()
+	if err != nil {
+		t.Fatalf("blobRefCounts failed: %v", err)
+	}
+	if err := m.gcUnreferencedBlobs(counts); err != nil {
+		t.Fatalf("gcUnreferencedBlobs failed: %v", err)
+	}
+
+	if _, err := os.Stat(sidecarPath(m.blobPath(referenced.Digest))); err != nil {
+		t.Errorf("expected the referenced blob's sidecar to survive GC: %v", err)
+	}
+	if _, err := os.Stat(sidecarPath(m.blobPath(orphan.Digest))); !os.IsNotExist(err) {
+		t.Error("expected the orphaned blob's sidecar to be removed by GC")
+	}
+}
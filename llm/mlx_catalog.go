@@ -0,0 +1,223 @@
+package llm
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// mlx_catalog.go is the curated-name half of pulling an MLX model, distinct
+// from mlx_gallery.go's GalleryConfig (which picks *where* a name is pulled
+// from). An MLXCatalog maps short, curated names like "gemma3:270m-4bit" to
+// a full HuggingFace repo plus the metadata ollmlx needs to run it well
+// (minimum MLX version, quantization, context length, prompt template), the
+// same role LocalAI's model gallery index plays for its backends.
+
+// MLXCatalogEntry is one curated model listed in an MLXCatalog.
+type MLXCatalogEntry struct {
+	Name           string `yaml:"name" json:"name"`
+	Repo           string `yaml:"repo" json:"repo"`
+	Description    string `yaml:"description,omitempty" json:"description,omitempty"`
+	MinMLXVersion  string `yaml:"min_mlx_version,omitempty" json:"min_mlx_version,omitempty"`
+	Quantization   string `yaml:"quantization,omitempty" json:"quantization,omitempty"`
+	ContextLength  int    `yaml:"context_length,omitempty" json:"context_length,omitempty"`
+	PromptTemplate string `yaml:"prompt_template,omitempty" json:"prompt_template,omitempty"`
+}
+
+// MLXCatalog is the on-the-wire shape of a gallery index: a flat list of
+// curated entries, optionally signed by a sidecar ".sha256" file at the same
+// URL.
+type MLXCatalog struct {
+	Models []MLXCatalogEntry `yaml:"models" json:"models"`
+}
+
+// Find looks up name by its curated catalog name, case-sensitively, the
+// same way users type it on the CLI.
+func (c *MLXCatalog) Find(name string) (MLXCatalogEntry, bool) {
+	for _, e := range c.Models {
+		if e.Name == name {
+			return e, true
+		}
+	}
+	return MLXCatalogEntry{}, false
+}
+
+// Search returns every entry whose name, repo, or description contains
+// query, case-insensitively. An empty query matches everything.
+func (c *MLXCatalog) Search(query string) []MLXCatalogEntry {
+	query = strings.ToLower(query)
+	if query == "" {
+		return c.Models
+	}
+
+	var matches []MLXCatalogEntry
+	for _, e := range c.Models {
+		if strings.Contains(strings.ToLower(e.Name), query) ||
+			strings.Contains(strings.ToLower(e.Repo), query) ||
+			strings.Contains(strings.ToLower(e.Description), query) {
+			matches = append(matches, e)
+		}
+	}
+	return matches
+}
+
+// bundledMLXCatalog is shipped with ollmlx so gallery list|search|info work
+// with no network access, and so LoadMLXCatalog always has a usable default
+// before any OLLMLX_GALLERIES URL is configured.
+func bundledMLXCatalog() *MLXCatalog {
+	return &MLXCatalog{Models: []MLXCatalogEntry{
+		{Name: "gemma3:270m-4bit", Repo: "mlx-community/gemma-3-270m-4bit", Quantization: "4bit", ContextLength: 8192, PromptTemplate: "gemma"},
+		{Name: "qwen2.5:7b-4bit", Repo: "mlx-community/Qwen2.5-7B-Instruct-4bit", Quantization: "4bit", ContextLength: 32768, PromptTemplate: "qwen"},
+		{Name: "llama3.2:3b-4bit", Repo: "mlx-community/Llama-3.2-3B-Instruct-4bit", Quantization: "4bit", ContextLength: 8192, PromptTemplate: "llama"},
+		{Name: "mistral:7b-4bit", Repo: "mlx-community/Mistral-7B-Instruct-v0.3-4bit", Quantization: "4bit", ContextLength: 32768, PromptTemplate: "mistral"},
+	}}
+}
+
+// catalogURLs returns the gallery index URLs to fetch, from the
+// comma-separated OLLMLX_GALLERIES env var. Empty when unset: the bundled
+// catalog is used on its own.
+func catalogURLs() []string {
+	raw := os.Getenv("OLLMLX_GALLERIES")
+	if raw == "" {
+		return nil
+	}
+
+	var urls []string
+	for _, u := range strings.Split(raw, ",") {
+		if u = strings.TrimSpace(u); u != "" {
+			urls = append(urls, u)
+		}
+	}
+	return urls
+}
+
+// LoadMLXCatalog merges the bundled catalog with every index listed in
+// OLLMLX_GALLERIES, later sources overriding entries with the same Name. A
+// URL that fails to fetch or verify is logged via the returned error's
+// wrapping chain but doesn't prevent the other sources (or the bundled
+// catalog) from being used.
+func LoadMLXCatalog(ctx context.Context) (*MLXCatalog, error) {
+	merged := map[string]MLXCatalogEntry{}
+	for _, e := range bundledMLXCatalog().Models {
+		merged[e.Name] = e
+	}
+
+	var fetchErrs []error
+	for _, url := range catalogURLs() {
+		remote, err := fetchMLXCatalog(ctx, url)
+		if err != nil {
+			fetchErrs = append(fetchErrs, fmt.Errorf("gallery %s: %w", url, err))
+			continue
+		}
+		for _, e := range remote.Models {
+			merged[e.Name] = e
+		}
+	}
+
+	cat := &MLXCatalog{}
+	for _, e := range merged {
+		cat.Models = append(cat.Models, e)
+	}
+
+	if len(fetchErrs) > 0 {
+		return cat, fmt.Errorf("%d of %d galleries failed to load: %w", len(fetchErrs), len(catalogURLs()), fetchErrs[0])
+	}
+	return cat, nil
+}
+
+// fetchMLXCatalog downloads a catalog index from url and, when a
+// "<url>.sha256" sidecar exists, verifies the index's content against it
+// before parsing. The index may be YAML or JSON; it's distinguished by
+// attempting JSON first.
+func fetchMLXCatalog(ctx context.Context, url string) (*MLXCatalog, error) {
+	data, err := fetchMLXCatalogBytes(ctx, url)
+	if err != nil {
+		return nil, err
+	}
+
+	if sum, err := fetchMLXCatalogBytes(ctx, url+".sha256"); err == nil {
+		got := sha256.Sum256(data)
+		want := strings.Fields(string(sum))
+		if len(want) == 0 || hex.EncodeToString(got[:]) != strings.ToLower(want[0]) {
+			return nil, fmt.Errorf("checksum mismatch against %s.sha256", url)
+		}
+	}
+
+	var cat MLXCatalog
+	if err := json.Unmarshal(data, &cat); err == nil {
+		return &cat, nil
+	}
+	if err := yaml.Unmarshal(data, &cat); err != nil {
+		return nil, fmt.Errorf("failed to parse gallery index: %w", err)
+	}
+	return &cat, nil
+}
+
+func fetchMLXCatalogBytes(ctx context.Context, url string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("server returned %d", resp.StatusCode)
+	}
+	return io.ReadAll(io.LimitReader(resp.Body, 10<<20))
+}
+
+// catalogSidecarPath is where PersistCatalogEntry writes the resolved entry
+// alongside a downloaded model (server.PullMLXModel does so after pulling a
+// short catalog name), for LoadPersistedCatalogEntry to read back without a
+// network round-trip.
+func catalogSidecarPath(modelPath string) string {
+	return filepath.Join(modelPath, "ollmlx_gallery.json")
+}
+
+// PersistCatalogEntry writes entry next to modelPath so
+// LoadPersistedCatalogEntry can read it back without hitting the network
+// again. Neither the chat template loader nor `ollmlx gallery info` reads
+// this file.
+func PersistCatalogEntry(modelPath string, entry MLXCatalogEntry) error {
+	data, err := json.MarshalIndent(entry, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(catalogSidecarPath(modelPath), data, 0644)
+}
+
+// LoadPersistedCatalogEntry reads back whatever PersistCatalogEntry wrote
+// for modelPath, if anything.
+func LoadPersistedCatalogEntry(modelPath string) (MLXCatalogEntry, bool) {
+	data, err := os.ReadFile(catalogSidecarPath(modelPath))
+	if err != nil {
+		return MLXCatalogEntry{}, false
+	}
+	var entry MLXCatalogEntry
+	if err := json.Unmarshal(data, &entry); err != nil {
+		return MLXCatalogEntry{}, false
+	}
+	return entry, true
+}
+
+// IsShortCatalogName reports whether name looks like a curated catalog name
+// ("gemma3:270m-4bit") rather than a full HuggingFace reference
+// ("mlx-community/gemma-3-270m-4bit"): it has no "/" the way an org/repo
+// pair always does.
+func IsShortCatalogName(name string) bool {
+	return !strings.Contains(name, "/")
+}
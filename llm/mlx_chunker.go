@@ -0,0 +1,420 @@
+package llm
+
+import (
+	"bufio"
+	"context"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// Content-defined chunk size band, per the FastCDC-style gear-hash chunker
+// below: chunks normally land between 64 KiB and 1 MiB.
+const (
+	cdcMinChunkSize = 64 * 1024
+	cdcMaxChunkSize = 1024 * 1024
+	cdcMask         = 1<<17 - 1 // ~128 KiB average cut point
+)
+
+// gearTable is the rolling-hash lookup table used by the chunker. It only
+// needs to be a fixed pseudo-random permutation of byte values, so it's
+// derived once from a constant seed rather than loaded from disk.
+var gearTable = buildGearTable()
+
+func buildGearTable() [256]uint64 {
+	var table [256]uint64
+	h := sha256.New()
+	for i := range table {
+		h.Reset()
+		h.Write([]byte("ollmlx-fastcdc-gear-table"))
+		h.Write([]byte{byte(i)})
+		table[i] = binary.BigEndian.Uint64(h.Sum(nil))
+	}
+	return table
+}
+
+// chunkRecord describes one content-defined chunk of a file.
+type chunkRecord struct {
+	Offset int64  `json:"offset"`
+	Length int64  `json:"length"`
+	SHA256 string `json:"sha256"`
+}
+
+// chunkSidecar is the "<digest>.chunks.json" file stored alongside a blob:
+// enough to reassemble it from a mix of local chunks and ranged HTTP
+// fetches.
+type chunkSidecar struct {
+	Digest string        `json:"digest"`
+	Size   int64         `json:"size"`
+	Chunks []chunkRecord `json:"chunks"`
+}
+
+func chunkedPullEnabled() bool {
+	return os.Getenv("OLLMLX_CHUNKED_PULL") == "1"
+}
+
+// chunkFile runs path through the gear-hash content-defined chunker and
+// returns the resulting sidecar.
+func chunkFile(path string) (*chunkSidecar, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	stat, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		chunks []chunkRecord
+		offset int64
+		hash   uint64
+		buf    = make([]byte, 0, cdcMaxChunkSize)
+		reader = bufio.NewReaderSize(f, 1<<20)
+	)
+
+	cut := func() {
+		sum := sha256.Sum256(buf)
+		chunks = append(chunks, chunkRecord{Offset: offset, Length: int64(len(buf)), SHA256: hex.EncodeToString(sum[:])})
+		offset += int64(len(buf))
+		buf = buf[:0]
+		hash = 0
+	}
+
+	for {
+		b, err := reader.ReadByte()
+		if err == nil {
+			buf = append(buf, b)
+			hash = (hash << 1) + gearTable[b]
+			if (len(buf) >= cdcMinChunkSize && hash&cdcMask == 0) || len(buf) >= cdcMaxChunkSize {
+				cut()
+			}
+			continue
+		}
+		if err == io.EOF {
+			break
+		}
+		return nil, err
+	}
+	if len(buf) > 0 {
+		cut()
+	}
+
+	return &chunkSidecar{Size: stat.Size(), Chunks: chunks}, nil
+}
+
+func sidecarPath(blobPath string) string {
+	return blobPath + ".chunks.json"
+}
+
+func readSidecar(path string) (*chunkSidecar, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var sidecar chunkSidecar
+	if err := json.Unmarshal(data, &sidecar); err != nil {
+		return nil, err
+	}
+	return &sidecar, nil
+}
+
+func writeSidecar(path string, sidecar *chunkSidecar) error {
+	data, err := json.Marshal(sidecar)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0644)
+}
+
+// chunkLocation points at where a previously-seen chunk's bytes live on
+// disk: a byte range inside an existing blob.
+type chunkLocation struct {
+	BlobDigest string
+	Offset     int64
+	Length     int64
+}
+
+// chunkIndex maps a chunk's content digest to its location in the blob
+// store. It is rebuildable from the "*.chunks.json" sidecars under
+// blobsRoot, so it never needs to be persisted itself.
+type chunkIndex struct {
+	mu       sync.RWMutex
+	byDigest map[string]chunkLocation
+}
+
+func newChunkIndex() *chunkIndex {
+	return &chunkIndex{byDigest: make(map[string]chunkLocation)}
+}
+
+func (idx *chunkIndex) add(blobDigest string, chunks []chunkRecord) {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+	for _, c := range chunks {
+		idx.byDigest[c.SHA256] = chunkLocation{BlobDigest: blobDigest, Offset: c.Offset, Length: c.Length}
+	}
+}
+
+func (idx *chunkIndex) lookup(digest string) (chunkLocation, bool) {
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+	loc, ok := idx.byDigest[digest]
+	return loc, ok
+}
+
+// rebuildChunkIndex scans every "*.chunks.json" sidecar under blobsRoot and
+// (re)populates idx. Safe to call repeatedly; later entries simply overwrite
+// earlier ones for the same digest.
+func (m *MLXModelManager) rebuildChunkIndex() {
+	entries, err := os.ReadDir(m.blobsRoot())
+	if err != nil {
+		return
+	}
+	for _, entry := range entries {
+		name := entry.Name()
+		if !strings.HasSuffix(name, ".chunks.json") {
+			continue
+		}
+		blobDigest := "sha256:" + strings.TrimSuffix(name, ".chunks.json")
+		sidecar, err := readSidecar(filepath.Join(m.blobsRoot(), name))
+		if err != nil {
+			continue
+		}
+		m.chunkIdx.add(blobDigest, sidecar.Chunks)
+	}
+}
+
+// ensureChunkSidecar generates and persists a chunks.json for a newly
+// stored blob, and registers its chunks into the in-memory index, so a
+// later pull of a sibling model sharing some of these bytes can reuse them.
+// Only worth doing for the large sharded weight files chunked pulls target.
+func (m *MLXModelManager) ensureChunkSidecar(desc blobDescriptor) {
+	if !strings.HasSuffix(strings.ToLower(desc.Filename), ".safetensors") {
+		return
+	}
+
+	blobPath := m.blobPath(desc.Digest)
+	scPath := sidecarPath(blobPath)
+	if _, err := os.Stat(scPath); err == nil {
+		return
+	}
+
+	sidecar, err := chunkFile(blobPath)
+	if err != nil {
+		return
+	}
+	sidecar.Digest = desc.Digest
+
+	if err := writeSidecar(scPath, sidecar); err != nil {
+		return
+	}
+	m.chunkIdx.add(desc.Digest, sidecar.Chunks)
+}
+
+// fetchRemoteSidecar looks for a "<file>.chunks.json" published alongside
+// the shard itself. Most HuggingFace repos won't have one, so a 404 here is
+// the common case and simply means the caller should fall back.
+func (m *MLXModelManager) fetchRemoteSidecar(ctx context.Context, client *http.Client, fileURL, token string) (*chunkSidecar, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, fileURL+".chunks.json", nil)
+	if err != nil {
+		return nil, err
+	}
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		io.Copy(io.Discard, resp.Body)
+		return nil, nil
+	}
+
+	var sidecar chunkSidecar
+	if err := json.NewDecoder(resp.Body).Decode(&sidecar); err != nil {
+		return nil, nil
+	}
+	return &sidecar, nil
+}
+
+// offsetWriter adapts sequential io.Copy-style writes into WriteAt calls at
+// an advancing file offset, so fetchMissingChunks can stream each ranged
+// HTTP response straight into the right place in the preallocated file.
+type offsetWriter struct {
+	f   *os.File
+	off int64
+}
+
+func (w *offsetWriter) Write(p []byte) (int, error) {
+	n, err := w.f.WriteAt(p, w.off)
+	w.off += int64(n)
+	return n, err
+}
+
+// downloadFileChunked assembles destPath from a mix of bytes already
+// present in the local blob store and ranged HTTP fetches for whatever is
+// missing, guided by a sidecar describing the remote file's chunk
+// boundaries. It returns (false, nil) whenever chunked assembly isn't
+// applicable or can't be completed cleanly (no sidecar, a failed local copy
+// or ranged fetch), so the caller falls back to the existing whole-file
+// downloadFileWithSize path. It returns (false, err) when the destination
+// directory or ".part" file can't be created, or the finished file can't
+// be renamed into place.
+func (m *MLXModelManager) downloadFileChunked(ctx context.Context, client *http.Client, token, fileURL, destPath string, progress func(int64, int64)) (bool, error) {
+	if !chunkedPullEnabled() || !strings.HasSuffix(strings.ToLower(destPath), ".safetensors") {
+		return false, nil
+	}
+
+	sidecar, err := m.fetchRemoteSidecar(ctx, client, fileURL, token)
+	if err != nil || sidecar == nil || len(sidecar.Chunks) == 0 {
+		return false, nil
+	}
+
+	if err := os.MkdirAll(filepath.Dir(destPath), 0755); err != nil {
+		return false, err
+	}
+
+	tmpPath := destPath + ".part"
+	out, err := os.Create(tmpPath)
+	if err != nil {
+		return false, err
+	}
+	defer out.Close()
+	if err := out.Truncate(sidecar.Size); err != nil {
+		os.Remove(tmpPath)
+		return false, nil
+	}
+
+	var resolved int64
+	var missing []chunkRecord
+
+	for _, c := range sidecar.Chunks {
+		loc, ok := m.chunkIdx.lookup(c.SHA256)
+		if !ok {
+			missing = append(missing, c)
+			continue
+		}
+		if err := copyLocalChunk(out, m.blobPath(loc.BlobDigest), loc, c.Offset); err != nil {
+			os.Remove(tmpPath)
+			return false, nil
+		}
+		resolved += c.Length
+		if progress != nil {
+			progress(resolved, sidecar.Size)
+		}
+	}
+
+	if len(missing) > 0 {
+		if err := m.fetchMissingChunks(ctx, client, token, fileURL, out, missing, sidecar.Size, &resolved, progress); err != nil {
+			os.Remove(tmpPath)
+			return false, nil
+		}
+	}
+
+	out.Close()
+	if err := os.Rename(tmpPath, destPath); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+func copyLocalChunk(out *os.File, srcPath string, loc chunkLocation, destOffset int64) error {
+	src, err := os.Open(srcPath)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	buf := make([]byte, loc.Length)
+	if _, err := src.ReadAt(buf, loc.Offset); err != nil && err != io.EOF {
+		return err
+	}
+	_, err = out.WriteAt(buf, destOffset)
+	return err
+}
+
+// fetchMissingChunks coalesces contiguous missing chunks into single Range
+// requests and streams each response directly into out at the right offset.
+// Once a coalesced range lands on disk, every chunk record it covers is
+// read back and re-hashed against its sidecar-declared SHA256 before being
+// trusted: a corrupted or tampered range response would otherwise get
+// silently stored and registered into the chunk index, ready to poison
+// every future pull that reuses it as a "local" chunk.
+func (m *MLXModelManager) fetchMissingChunks(ctx context.Context, client *http.Client, token, fileURL string, out *os.File, missing []chunkRecord, total int64, resolved *int64, progress func(int64, int64)) error {
+	for i := 0; i < len(missing); {
+		start := missing[i].Offset
+		end := missing[i].Offset + missing[i].Length - 1
+		j := i + 1
+		for j < len(missing) && missing[j].Offset == end+1 {
+			end = missing[j].Offset + missing[j].Length - 1
+			j++
+		}
+
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, fileURL, nil)
+		if err != nil {
+			return err
+		}
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		req.Header.Set("Range", fmt.Sprintf("bytes=%d-%d", start, end))
+
+		resp, err := client.Do(req)
+		if err != nil {
+			return err
+		}
+		if resp.StatusCode != http.StatusPartialContent {
+			resp.Body.Close()
+			return fmt.Errorf("server does not support ranged requests (status %d)", resp.StatusCode)
+		}
+
+		w := &offsetWriter{f: out, off: start}
+		if _, err := io.Copy(w, resp.Body); err != nil {
+			resp.Body.Close()
+			return err
+		}
+		resp.Body.Close()
+
+		if err := verifyChunks(out, missing[i:j]); err != nil {
+			return err
+		}
+
+		*resolved += end - start + 1
+		if progress != nil {
+			progress(*resolved, total)
+		}
+
+		i = j
+	}
+	return nil
+}
+
+// verifyChunks re-reads each of chunks from out and confirms its content
+// still hashes to the digest the sidecar declared for it.
+func verifyChunks(out *os.File, chunks []chunkRecord) error {
+	for _, c := range chunks {
+		buf := make([]byte, c.Length)
+		if _, err := out.ReadAt(buf, c.Offset); err != nil {
+			return fmt.Errorf("failed to read back fetched chunk at offset %d: %w", c.Offset, err)
+		}
+		sum := sha256.Sum256(buf)
+		if got := hex.EncodeToString(sum[:]); got != c.SHA256 {
+			return fmt.Errorf("fetched chunk at offset %d failed digest verification: got %s, want %s", c.Offset, got, c.SHA256)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,206 @@
+package llm
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// writeRandFile writes n pseudo-random bytes (seeded for reproducibility)
+// to a new file under t.TempDir() and returns its path and content.
+func writeRandFile(t *testing.T, n int) (string, []byte) {
+	t.Helper()
+
+	data := make([]byte, n)
+	rand.New(rand.NewSource(1)).Read(data)
+
+	path := filepath.Join(t.TempDir(), "blob.safetensors")
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		t.Fatalf("failed to write test file: %v", err)
+	}
+	return path, data
+}
+
+// TestChunkFileCutPoints verifies chunkFile's cut points are contiguous,
+// cover the whole file, and respect the configured min/max chunk size band
+// (except for a final chunk, which can be smaller than the minimum).
+func TestChunkFileCutPoints(t *testing.T) {
+	path, data := writeRandFile(t, 4*cdcMaxChunkSize)
+
+	sidecar, err := chunkFile(path)
+	if err != nil {
+		t.Fatalf("chunkFile failed: %v", err)
+	}
+
+	if sidecar.Size != int64(len(data)) {
+		t.Fatalf("sidecar size = %d, want %d", sidecar.Size, len(data))
+	}
+	if len(sidecar.Chunks) < 2 {
+		t.Fatalf("expected a 4x-max-chunk-size file to split into multiple chunks, got %d", len(sidecar.Chunks))
+	}
+
+	var offset int64
+	for i, c := range sidecar.Chunks {
+		if c.Offset != offset {
+			t.Fatalf("chunk %d offset = %d, want %d (chunks must be contiguous)", i, c.Offset, offset)
+		}
+		if c.Length > cdcMaxChunkSize {
+			t.Fatalf("chunk %d length %d exceeds cdcMaxChunkSize %d", i, c.Length, cdcMaxChunkSize)
+		}
+		last := i == len(sidecar.Chunks)-1
+		if !last && c.Length < cdcMinChunkSize {
+			t.Fatalf("non-final chunk %d length %d is under cdcMinChunkSize %d", i, c.Length, cdcMinChunkSize)
+		}
+
+		want := sha256.Sum256(data[c.Offset : c.Offset+c.Length])
+		if c.SHA256 != hex.EncodeToString(want[:]) {
+			t.Errorf("chunk %d digest mismatch", i)
+		}
+
+		offset += c.Length
+	}
+
+	if offset != int64(len(data)) {
+		t.Fatalf("chunks covered %d bytes, want %d", offset, len(data))
+	}
+}
+
+// TestChunkFileDeterministic verifies chunking the same content twice
+// produces identical cut points, since the chunk index relies on a chunk's
+// digest (and therefore its boundaries) being stable across pulls.
+func TestChunkFileDeterministic(t *testing.T) {
+	path, _ := writeRandFile(t, 2*cdcMaxChunkSize)
+
+	first, err := chunkFile(path)
+	if err != nil {
+		t.Fatalf("chunkFile failed: %v", err)
+	}
+	second, err := chunkFile(path)
+	if err != nil {
+		t.Fatalf("chunkFile failed: %v", err)
+	}
+
+	if len(first.Chunks) != len(second.Chunks) {
+		t.Fatalf("chunk count differs between runs: %d vs %d", len(first.Chunks), len(second.Chunks))
+	}
+	for i := range first.Chunks {
+		if first.Chunks[i] != second.Chunks[i] {
+			t.Errorf("chunk %d differs between runs: %+v vs %+v", i, first.Chunks[i], second.Chunks[i])
+		}
+	}
+}
+
+// TestChunkFileSmallerThanMinChunk verifies a file smaller than
+// cdcMinChunkSize yields a single chunk rather than an empty or truncated
+// one.
+func TestChunkFileSmallerThanMinChunk(t *testing.T) {
+	path, data := writeRandFile(t, 1024)
+
+	sidecar, err := chunkFile(path)
+	if err != nil {
+		t.Fatalf("chunkFile failed: %v", err)
+	}
+
+	if len(sidecar.Chunks) != 1 {
+		t.Fatalf("expected exactly one chunk for a sub-minimum file, got %d", len(sidecar.Chunks))
+	}
+	if sidecar.Chunks[0].Length != int64(len(data)) {
+		t.Fatalf("chunk length = %d, want %d", sidecar.Chunks[0].Length, len(data))
+	}
+}
+
+// TestCopyLocalChunkReassembly verifies copyLocalChunk reads the right byte
+// range out of an existing blob and writes it at the right offset in the
+// destination file, the building block downloadFileChunked uses to
+// reassemble a file from chunks it already has locally.
+func TestCopyLocalChunkReassembly(t *testing.T) {
+	srcPath, data := writeRandFile(t, 3*cdcMinChunkSize)
+
+	dstPath := filepath.Join(t.TempDir(), "out.safetensors")
+	dst, err := os.Create(dstPath)
+	if err != nil {
+		t.Fatalf("failed to create destination file: %v", err)
+	}
+	if err := dst.Truncate(int64(len(data))); err != nil {
+		t.Fatalf("failed to truncate destination file: %v", err)
+	}
+
+	// Copy the file's three thirds out of order to make sure copyLocalChunk
+	// really honors destOffset rather than just appending.
+	third := len(data) / 3
+	ranges := []chunkLocation{
+		{Offset: int64(2 * third), Length: int64(len(data) - 2*third)},
+		{Offset: 0, Length: int64(third)},
+		{Offset: int64(third), Length: int64(third)},
+	}
+	for _, loc := range ranges {
+		if err := copyLocalChunk(dst, srcPath, loc, loc.Offset); err != nil {
+			t.Fatalf("copyLocalChunk failed: %v", err)
+		}
+	}
+	dst.Close()
+
+	got, err := os.ReadFile(dstPath)
+	if err != nil {
+		t.Fatalf("failed to read reassembled file: %v", err)
+	}
+	if string(got) != string(data) {
+		t.Fatal("reassembled file does not match original content")
+	}
+}
+
+// TestVerifyChunksDetectsCorruption verifies verifyChunks rejects a fetched
+// range whose bytes don't match its sidecar-declared digest, so a
+// corrupted or tampered response never gets trusted and registered into
+// the chunk index.
+func TestVerifyChunksDetectsCorruption(t *testing.T) {
+	data := []byte("the quick brown fox jumps over the lazy dog")
+	path := filepath.Join(t.TempDir(), "out")
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		t.Fatalf("failed to write test file: %v", err)
+	}
+	f, err := os.OpenFile(path, os.O_RDWR, 0644)
+	if err != nil {
+		t.Fatalf("failed to open test file: %v", err)
+	}
+	defer f.Close()
+
+	want := sha256.Sum256(data)
+	good := chunkRecord{Offset: 0, Length: int64(len(data)), SHA256: hex.EncodeToString(want[:])}
+	if err := verifyChunks(f, []chunkRecord{good}); err != nil {
+		t.Fatalf("verifyChunks rejected a correctly hashed chunk: %v", err)
+	}
+
+	wrongDigest := chunkRecord{Offset: 0, Length: int64(len(data)), SHA256: strings.Repeat("0", 64)}
+	if err := verifyChunks(f, []chunkRecord{wrongDigest}); err == nil {
+		t.Fatal("expected verifyChunks to reject a chunk whose content doesn't match the declared digest")
+	}
+}
+
+// TestChunkIndexAddLookup verifies the chunk index round-trips a chunk's
+// digest to its blob location, and reports a miss for anything never added.
+func TestChunkIndexAddLookup(t *testing.T) {
+	idx := newChunkIndex()
+
+	chunks := []chunkRecord{
+		{Offset: 0, Length: 100, SHA256: "aaa"},
+		{Offset: 100, Length: 200, SHA256: "bbb"},
+	}
+	idx.add("sha256:blobdigest", chunks)
+
+	loc, ok := idx.lookup("bbb")
+	if !ok {
+		t.Fatal("expected lookup to find a chunk added via add")
+	}
+	if loc.BlobDigest != "sha256:blobdigest" || loc.Offset != 100 || loc.Length != 200 {
+		t.Errorf("lookup returned %+v, want BlobDigest=sha256:blobdigest Offset=100 Length=200", loc)
+	}
+
+	if _, ok := idx.lookup("never-added"); ok {
+		t.Error("expected lookup to miss for a digest that was never added")
+	}
+}
@@ -0,0 +1,271 @@
+package llm
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/gob"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+)
+
+// usageCacheEntry is what the crawler records per model directory, modeled
+// on minio's data-usage cache: enough for ListModels/GetModelInfo to answer
+// without touching the filesystem.
+type usageCacheEntry struct {
+	Size         int64
+	FileCount    int
+	Digest       string
+	LastScanned  time.Time
+	PerFileSizes map[string]int64
+}
+
+// usageCache is the gob-encoded "usage.cache" file written at modelsDir's
+// root.
+type usageCache struct {
+	Entries map[string]usageCacheEntry
+}
+
+func usageCachePath(modelsDir string) string {
+	return filepath.Join(modelsDir, "usage.cache")
+}
+
+func loadUsageCache(modelsDir string) *usageCache {
+	f, err := os.Open(usageCachePath(modelsDir))
+	if err != nil {
+		return &usageCache{Entries: make(map[string]usageCacheEntry)}
+	}
+	defer f.Close()
+
+	var c usageCache
+	if err := gob.NewDecoder(f).Decode(&c); err != nil || c.Entries == nil {
+		return &usageCache{Entries: make(map[string]usageCacheEntry)}
+	}
+	return &c
+}
+
+func (m *MLXModelManager) saveUsageCache() error {
+	tmp := usageCachePath(m.modelsDir) + ".tmp"
+	f, err := os.Create(tmp)
+	if err != nil {
+		return err
+	}
+
+	m.usageMu.RLock()
+	encErr := gob.NewEncoder(f).Encode(m.usage)
+	m.usageMu.RUnlock()
+
+	f.Close()
+	if encErr != nil {
+		os.Remove(tmp)
+		return encErr
+	}
+	return os.Rename(tmp, usageCachePath(m.modelsDir))
+}
+
+func crawlInterval() time.Duration {
+	if v := os.Getenv("OLLMLX_CRAWL_INTERVAL"); v != "" {
+		if d, err := time.ParseDuration(v); err == nil && d > 0 {
+			return d
+		}
+	}
+	return 12 * time.Hour
+}
+
+func usageCacheTTL() time.Duration {
+	return crawlInterval()
+}
+
+// StartCrawler launches the single background goroutine that keeps
+// usage.cache warm: one scan at startup, then one per crawlInterval. It's
+// meant to be called once, against the one long-lived MLXModelManager a
+// process keeps around (see defaultBackendRegistry), not against the
+// short-lived managers most request handlers construct - calling it
+// repeatedly against throwaway managers would leak a goroutine and ticker
+// per call, so a second call on the same manager is a no-op.
+func (m *MLXModelManager) StartCrawler() {
+	m.crawlerOnce.Do(func() {
+		go func() {
+			m.Refresh(context.Background())
+
+			ticker := time.NewTicker(crawlInterval())
+			defer ticker.Stop()
+			for range ticker.C {
+				m.Refresh(context.Background())
+			}
+		}()
+	})
+}
+
+// Refresh performs a full, bounded-parallel rescan of modelsDir and rewrites
+// usage.cache. It's what the background crawler calls on its interval, and
+// what DownloadMLXModel/DeleteModel trigger (via refreshOne, a cheaper
+// single-directory variant) after a successful mutation.
+func (m *MLXModelManager) Refresh(ctx context.Context) error {
+	entries, err := os.ReadDir(m.modelsDir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+
+	var names []string
+	for _, entry := range entries {
+		if !entry.IsDir() || internalDirs[entry.Name()] {
+			continue
+		}
+		names = append(names, entry.Name())
+	}
+
+	if err := m.refreshDelta(ctx, names); err != nil {
+		return err
+	}
+	return m.saveUsageCache()
+}
+
+// refreshDelta updates the cache entries for exactly the given model
+// directory names, leaving everything else untouched. This is the "delta"
+// mode: a targeted rescan of dirs known to have changed, without paying for
+// a full crawl.
+func (m *MLXModelManager) refreshDelta(ctx context.Context, fsNames []string) error {
+	for _, name := range fsNames {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		modelPath := filepath.Join(m.modelsDir, name)
+		size, fileCount, perFile, err := fastWalkSize(modelPath)
+		if err != nil {
+			m.usageMu.Lock()
+			delete(m.usage.Entries, name)
+			m.usageMu.Unlock()
+			continue
+		}
+
+		entry := usageCacheEntry{
+			Size:         size,
+			FileCount:    fileCount,
+			Digest:       digestFromPerFile(perFile),
+			LastScanned:  time.Now(),
+			PerFileSizes: perFile,
+		}
+
+		m.usageMu.Lock()
+		m.usage.Entries[name] = entry
+		m.usageMu.Unlock()
+	}
+	return nil
+}
+
+// refreshOne is the cheap, single-directory path DownloadMLXModel and
+// DeleteModel call after a successful mutation, so the next ListModels call
+// doesn't serve a stale entry until the next full crawl.
+func (m *MLXModelManager) refreshOne(fsName string) {
+	_ = m.refreshDelta(context.Background(), []string{fsName})
+	_ = m.saveUsageCache()
+}
+
+// lookupUsage returns the cached entry for fsName, and whether it's still
+// fresh enough (within usageCacheTTL) to trust without a live walk.
+func (m *MLXModelManager) lookupUsage(fsName string) (usageCacheEntry, bool) {
+	m.usageMu.RLock()
+	entry, ok := m.usage.Entries[fsName]
+	m.usageMu.RUnlock()
+
+	if !ok {
+		return usageCacheEntry{}, false
+	}
+	return entry, time.Since(entry.LastScanned) < usageCacheTTL()
+}
+
+func digestFromPerFile(perFile map[string]int64) string {
+	names := make([]string, 0, len(perFile))
+	for name := range perFile {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	h := sha256.New()
+	for _, name := range names {
+		fmt.Fprintf(h, "%s:%d\n", name, perFile[name])
+	}
+	return fmt.Sprintf("sha256:%x", h.Sum(nil))
+}
+
+// fastWalkSize is a bounded-parallel, fastwalk-style replacement for
+// filepath.Walk: a worker pool of os.ReadDir calls rather than one
+// goroutine descending the tree serially, so cold scans of large model
+// directories finish in a fraction of the time.
+func fastWalkSize(root string) (size int64, fileCount int, perFile map[string]int64, err error) {
+	w := &dirWalker{
+		sem:     make(chan struct{}, fastWalkConcurrency()),
+		perFile: make(map[string]int64),
+	}
+	w.walk(root, "")
+	w.wg.Wait()
+
+	if w.err != nil {
+		return 0, 0, nil, w.err
+	}
+	return w.size, w.fileCount, w.perFile, nil
+}
+
+func fastWalkConcurrency() int {
+	return 8
+}
+
+type dirWalker struct {
+	sem chan struct{}
+	wg  sync.WaitGroup
+
+	mu        sync.Mutex
+	size      int64
+	fileCount int
+	perFile   map[string]int64
+	err       error
+
+	errOnce sync.Once
+}
+
+func (w *dirWalker) walk(dir, relPrefix string) {
+	w.wg.Add(1)
+	go w.walkOne(dir, relPrefix)
+}
+
+func (w *dirWalker) walkOne(dir, relPrefix string) {
+	defer w.wg.Done()
+
+	w.sem <- struct{}{}
+	entries, err := os.ReadDir(dir)
+	<-w.sem
+
+	if err != nil {
+		w.errOnce.Do(func() { w.err = err })
+		return
+	}
+
+	for _, entry := range entries {
+		full := filepath.Join(dir, entry.Name())
+		rel := filepath.Join(relPrefix, entry.Name())
+
+		if entry.IsDir() {
+			w.walk(full, rel)
+			continue
+		}
+
+		info, err := entry.Info()
+		if err != nil {
+			continue
+		}
+
+		w.mu.Lock()
+		w.size += info.Size()
+		w.fileCount++
+		w.perFile[rel] = info.Size()
+		w.mu.Unlock()
+	}
+}
@@ -0,0 +1,521 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// mlx_gallery.go generalizes MLXModelManager's HuggingFace-only pull path
+// into a small pluggable registry: a GalleryConfig lists sources in
+// priority order (HuggingFace, an OCI registry speaking Docker Registry v2
+// manifests, a local directory mirror, or a plain HTTP mirror), Resolve
+// picks the first source willing to vouch for a short name, and Install
+// pulls whatever Resolve returned, verifying it against the same
+// config.json + weights + SHA256 manifest shape DownloadMLXModel already
+// produces.
+
+// GallerySourceKind identifies where a gallery source pulls models from.
+type GallerySourceKind string
+
+const (
+	SourceHuggingFace GallerySourceKind = "huggingface"
+	SourceOCIRegistry GallerySourceKind = "oci"
+	SourceLocalMirror GallerySourceKind = "local"
+	SourceHTTPMirror  GallerySourceKind = "http"
+)
+
+// ChecksumPolicy controls how strictly Install verifies a pulled model
+// against the digests its source advertised.
+type ChecksumPolicy string
+
+const (
+	// ChecksumRequired re-hashes every layer of a model installed from a
+	// local mirror, HTTP mirror or OCI registry against its manifest and
+	// fails Install on a mismatch. A source that advertised no digest is
+	// not rejected, and HuggingFace installs are not re-hashed.
+	ChecksumRequired ChecksumPolicy = "required"
+	// ChecksumIfPresent verifies a digest when the source advertised one,
+	// but tolerates sources (like plain HuggingFace) that don't.
+	ChecksumIfPresent ChecksumPolicy = "if-present"
+	// ChecksumSkip never verifies content digests for this source.
+	ChecksumSkip ChecksumPolicy = "skip"
+)
+
+// GallerySource is one entry in a GalleryConfig's source list.
+type GallerySource struct {
+	Name     string            `yaml:"name"`
+	Kind     GallerySourceKind `yaml:"kind"`
+	BaseURL  string            `yaml:"base_url,omitempty"`
+	Path     string            `yaml:"path,omitempty"` // SourceLocalMirror only
+	Priority int               `yaml:"priority"`
+	Checksum ChecksumPolicy    `yaml:"checksum,omitempty"`
+	// AuthEnv names an environment variable holding a bearer token to send
+	// with requests to this source.
+	AuthEnv string `yaml:"auth_env,omitempty"`
+}
+
+func (s GallerySource) token() string {
+	if s.AuthEnv == "" {
+		return ""
+	}
+	return strings.TrimSpace(os.Getenv(s.AuthEnv))
+}
+
+// GalleryConfig is the on-disk YAML shape read from galleryConfigPath. It
+// lists every source ollmlx will try, in descending priority order, when
+// resolving a short model name.
+type GalleryConfig struct {
+	Sources []GallerySource `yaml:"sources"`
+}
+
+// defaultGalleryConfig is what a fresh ollmlx install uses before anyone
+// writes a gallery.yaml: HuggingFace only, matching DownloadMLXModel's
+// historical behavior.
+func defaultGalleryConfig() *GalleryConfig {
+	return &GalleryConfig{
+		Sources: []GallerySource{
+			{Name: "huggingface", Kind: SourceHuggingFace, BaseURL: "https://huggingface.co", Priority: 100, Checksum: ChecksumIfPresent},
+		},
+	}
+}
+
+// galleryConfigPath returns where ollmlx reads its GalleryConfig from,
+// overridable via OLLMLX_GALLERY_CONFIG for tests and non-standard layouts.
+func galleryConfigPath(modelsDir string) string {
+	if p := os.Getenv("OLLMLX_GALLERY_CONFIG"); p != "" {
+		return p
+	}
+	return filepath.Join(modelsDir, "gallery.yaml")
+}
+
+// LoadGalleryConfig reads a GalleryConfig from path, sorted by descending
+// priority. A missing file isn't an error: callers get the built-in
+// HuggingFace-only default.
+func LoadGalleryConfig(path string) (*GalleryConfig, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return defaultGalleryConfig(), nil
+		}
+		return nil, fmt.Errorf("failed to read gallery config %s: %w", path, err)
+	}
+
+	var cfg GalleryConfig
+	if err := yaml.Unmarshal(data, &cfg); err != nil {
+		return nil, fmt.Errorf("failed to parse gallery config %s: %w", path, err)
+	}
+	if len(cfg.Sources) == 0 {
+		return defaultGalleryConfig(), nil
+	}
+
+	sort.SliceStable(cfg.Sources, func(i, j int) bool {
+		return cfg.Sources[i].Priority > cfg.Sources[j].Priority
+	})
+	return &cfg, nil
+}
+
+// ModelRef is the result of resolving a short name against a GalleryConfig:
+// which source vouched for it, and the fully qualified location Install
+// should pull from.
+type ModelRef struct {
+	Name       string
+	Source     GallerySource
+	ResolvedID string // org/repo for HuggingFace, a registry reference for OCI, a relative path otherwise
+	Digest     string // sha256:..., when the source advertised one
+}
+
+// Resolve maps a short model name (e.g. "gemma-3-270m") to a ModelRef by
+// trying each configured source in priority order and returning the first
+// one that can account for the name.
+func (m *MLXModelManager) Resolve(ctx context.Context, name string) (ModelRef, error) {
+	cfg, err := LoadGalleryConfig(galleryConfigPath(m.modelsDir))
+	if err != nil {
+		return ModelRef{}, err
+	}
+
+	var lastErr error
+	for _, src := range cfg.Sources {
+		ref, err := resolveAgainstSource(ctx, src, name)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		return ref, nil
+	}
+
+	if lastErr != nil {
+		return ModelRef{}, fmt.Errorf("could not resolve %q against any gallery source: %w", name, lastErr)
+	}
+	return ModelRef{}, fmt.Errorf("could not resolve %q: no gallery sources configured", name)
+}
+
+// resolveAgainstSource checks whether src can plausibly serve name, without
+// necessarily downloading anything. HuggingFace and OCI sources are taken
+// on faith (their existence is confirmed by the pull itself); local and
+// HTTP mirrors are checked directly since they're cheap to probe.
+func resolveAgainstSource(ctx context.Context, src GallerySource, name string) (ModelRef, error) {
+	switch src.Kind {
+	case SourceHuggingFace:
+		repo := name
+		if !strings.Contains(repo, "/") {
+			repo = "mlx-community/" + repo
+		}
+		return ModelRef{Name: name, Source: src, ResolvedID: repo}, nil
+
+	case SourceOCIRegistry:
+		if src.BaseURL == "" {
+			return ModelRef{}, fmt.Errorf("oci source %q has no base_url", src.Name)
+		}
+		return ModelRef{Name: name, Source: src, ResolvedID: name}, nil
+
+	case SourceLocalMirror:
+		if src.Path == "" {
+			return ModelRef{}, fmt.Errorf("local source %q has no path", src.Name)
+		}
+		dir := filepath.Join(src.Path, name)
+		if err := verifyModelShape(dir); err != nil {
+			return ModelRef{}, fmt.Errorf("local mirror %q does not have %s: %w", src.Name, name, err)
+		}
+		return ModelRef{Name: name, Source: src, ResolvedID: name}, nil
+
+	case SourceHTTPMirror:
+		if src.BaseURL == "" {
+			return ModelRef{}, fmt.Errorf("http source %q has no base_url", src.Name)
+		}
+		return ModelRef{Name: name, Source: src, ResolvedID: name}, nil
+
+	default:
+		return ModelRef{}, fmt.Errorf("unknown gallery source kind %q", src.Kind)
+	}
+}
+
+// List reports every model Resolve could plausibly serve without actually
+// pulling anything: models already installed locally, plus whatever the
+// configured local mirrors currently hold.
+func (m *MLXModelManager) List() ([]ModelRef, error) {
+	var refs []ModelRef
+
+	installed, err := m.ListModels()
+	if err != nil {
+		return nil, err
+	}
+	for _, info := range installed {
+		refs = append(refs, ModelRef{Name: info.Name, Digest: info.Digest})
+	}
+
+	cfg, err := LoadGalleryConfig(galleryConfigPath(m.modelsDir))
+	if err != nil {
+		return nil, err
+	}
+
+	for _, src := range cfg.Sources {
+		if src.Kind != SourceLocalMirror || src.Path == "" {
+			continue
+		}
+		entries, err := os.ReadDir(src.Path)
+		if err != nil {
+			continue
+		}
+		for _, entry := range entries {
+			if !entry.IsDir() {
+				continue
+			}
+			if err := verifyModelShape(filepath.Join(src.Path, entry.Name())); err != nil {
+				continue
+			}
+			refs = append(refs, ModelRef{Name: entry.Name(), Source: src, ResolvedID: entry.Name()})
+		}
+	}
+
+	return refs, nil
+}
+
+// Install pulls the model ref resolved to, dispatching on its source kind,
+// and verifies the result has the config.json + weights shape ollmlx
+// expects before returning.
+func (m *MLXModelManager) Install(ctx context.Context, ref ModelRef, progressFn func(MLXDownloadProgress)) error {
+	name := ref.Name
+	var err error
+	switch ref.Source.Kind {
+	case SourceHuggingFace, "":
+		// The zero-value Kind ("") covers ModelRef values built straight
+		// from a HuggingFace repo name, as DownloadMLXModel's callers have
+		// always done.
+		id := ref.ResolvedID
+		if id == "" {
+			id = ref.Name
+		}
+		name = id
+		err = m.DownloadMLXModel(ctx, id, progressFn)
+
+	case SourceOCIRegistry:
+		err = m.installFromOCIRegistry(ctx, ref, progressFn)
+
+	case SourceLocalMirror:
+		err = m.installFromLocalMirror(ref)
+
+	case SourceHTTPMirror:
+		err = m.installFromHTTPMirror(ctx, ref, progressFn)
+
+	default:
+		return fmt.Errorf("unknown gallery source kind %q", ref.Source.Kind)
+	}
+	if err != nil {
+		return err
+	}
+	return verifyModelShape(m.GetModelPath(name))
+}
+
+// verifyModelShape checks the minimum a directory needs to be treated as a
+// usable MLX model: a readable config.json and either safetensors or npz
+// weights, mirroring what MLXModelManager.ModelExists already probes for
+// locally-cached models.
+func verifyModelShape(dir string) error {
+	configPath := filepath.Join(dir, "config.json")
+	data, err := os.ReadFile(configPath)
+	if err != nil {
+		return fmt.Errorf("missing config.json: %w", err)
+	}
+	var probe map[string]any
+	if err := json.Unmarshal(data, &probe); err != nil {
+		return fmt.Errorf("config.json is not valid JSON: %w", err)
+	}
+
+	if _, err := os.Stat(filepath.Join(dir, "model.safetensors")); err == nil {
+		return nil
+	}
+	if _, err := os.Stat(filepath.Join(dir, "weights.npz")); err == nil {
+		return nil
+	}
+
+	// Sharded safetensors models have no single model.safetensors file.
+	entries, err := os.ReadDir(dir)
+	if err == nil {
+		for _, entry := range entries {
+			if strings.HasSuffix(entry.Name(), ".safetensors") {
+				return nil
+			}
+		}
+	}
+
+	return fmt.Errorf("no model.safetensors, weights.npz, or sharded safetensors found")
+}
+
+// verifyManifestChecksums re-hashes every layer a manifest claims and fails
+// on the first mismatch. Used by sources whose ChecksumPolicy is
+// ChecksumRequired or ChecksumIfPresent once a digest is known.
+func verifyManifestChecksums(modelPath string, man *modelManifest) error {
+	for _, layer := range man.Layers {
+		digest, err := digestFile(filepath.Join(modelPath, layer.Filename))
+		if err != nil {
+			return fmt.Errorf("failed to hash %s: %w", layer.Filename, err)
+		}
+		if digest != layer.Digest {
+			return fmt.Errorf("checksum mismatch for %s: manifest says %s, got %s", layer.Filename, layer.Digest, digest)
+		}
+	}
+	return nil
+}
+
+// installFromLocalMirror copies a model directory from a local mirror
+// source into the manager's flat layout, verifying its shape and computing
+// a fresh manifest so it's indistinguishable from a HuggingFace pull
+// afterwards.
+func (m *MLXModelManager) installFromLocalMirror(ref ModelRef) error {
+	srcDir := filepath.Join(ref.Source.Path, ref.ResolvedID)
+	if err := verifyModelShape(srcDir); err != nil {
+		return fmt.Errorf("local mirror source is invalid: %w", err)
+	}
+
+	modelPath := m.GetModelPath(ref.Name)
+	if err := os.MkdirAll(modelPath, 0755); err != nil {
+		return fmt.Errorf("failed to create model directory: %w", err)
+	}
+
+	entries, err := os.ReadDir(srcDir)
+	if err != nil {
+		return err
+	}
+
+	var layers []blobDescriptor
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+		// storeBlobFromFile moves its source into the blob store, which
+		// would destroy the mirror's copy, so hand it a staged duplicate
+		// instead of the mirror file itself.
+		staged, err := m.stageMirrorFile(filepath.Join(srcDir, entry.Name()))
+		if err != nil {
+			return fmt.Errorf("failed to stage %s: %w", entry.Name(), err)
+		}
+		desc, err := m.storeBlobFromFile(staged, entry.Name())
+		if err != nil {
+			return fmt.Errorf("failed to store %s: %w", entry.Name(), err)
+		}
+		layers = append(layers, desc)
+	}
+
+	man := &modelManifest{SchemaVersion: 2, Layers: layers}
+	for _, desc := range man.Layers {
+		if err := m.linkBlobIntoModel(modelPath, desc); err != nil {
+			return fmt.Errorf("failed to link %s: %w", desc.Filename, err)
+		}
+	}
+
+	if ref.Source.Checksum == ChecksumRequired || (ref.Source.Checksum == ChecksumIfPresent && ref.Digest != "") {
+		if err := verifyManifestChecksums(modelPath, man); err != nil {
+			os.RemoveAll(modelPath)
+			return err
+		}
+	}
+
+	return writeManifest(modelPath, man)
+}
+
+// stageMirrorFile copies src into blobsTmpDir so storeBlobFromFile's
+// rename-into-the-blob-store can consume it without touching the local
+// mirror's own copy.
+func (m *MLXModelManager) stageMirrorFile(src string) (string, error) {
+	if err := os.MkdirAll(m.blobsTmpDir(), 0755); err != nil {
+		return "", err
+	}
+
+	dst := filepath.Join(m.blobsTmpDir(), filepath.Base(src))
+	in, err := os.Open(src)
+	if err != nil {
+		return "", err
+	}
+	defer in.Close()
+
+	out, err := os.Create(dst)
+	if err != nil {
+		return "", err
+	}
+	defer out.Close()
+
+	if _, err := io.Copy(out, in); err != nil {
+		os.Remove(dst)
+		return "", err
+	}
+
+	return dst, nil
+}
+
+// installFromHTTPMirror pulls a model's file list from
+// <base_url>/<resolved_id>/manifest.json (the same modelManifest shape
+// ollmlx writes for its own pulls) and downloads each layer.
+func (m *MLXModelManager) installFromHTTPMirror(ctx context.Context, ref ModelRef, progressFn func(MLXDownloadProgress)) error {
+	baseURL := strings.TrimSuffix(ref.Source.BaseURL, "/") + "/" + ref.ResolvedID
+
+	man, err := fetchRemoteManifest(ctx, baseURL+"/manifest.json", ref.Source.token())
+	if err != nil {
+		return fmt.Errorf("failed to fetch manifest from %s: %w", ref.Source.Name, err)
+	}
+
+	modelPath := m.GetModelPath(ref.Name)
+	if err := os.MkdirAll(modelPath, 0755); err != nil {
+		return fmt.Errorf("failed to create model directory: %w", err)
+	}
+
+	client := &http.Client{}
+	for _, layer := range man.Layers {
+		destPath := filepath.Join(modelPath, layer.Filename)
+		fileURL := baseURL + "/" + layer.Filename
+		if err := m.downloadFile(ctx, client, fileURL, destPath, layer.Size, func(n int64) {
+			if progressFn != nil {
+				progressFn(MLXDownloadProgress{Filename: layer.Filename, Completed: n, Total: layer.Size})
+			}
+		}); err != nil {
+			os.RemoveAll(modelPath)
+			return fmt.Errorf("failed to download %s: %w", layer.Filename, err)
+		}
+	}
+
+	if ref.Source.Checksum == ChecksumRequired || (ref.Source.Checksum == ChecksumIfPresent && len(man.Layers) > 0) {
+		if err := verifyManifestChecksums(modelPath, man); err != nil {
+			os.RemoveAll(modelPath)
+			return err
+		}
+	}
+
+	return writeManifest(modelPath, man)
+}
+
+// installFromOCIRegistry speaks just enough Docker Registry v2 to pull a
+// model published as an ollama-style manifest: GET .../manifests/latest for
+// the layer list, then GET .../blobs/<digest> for each one.
+func (m *MLXModelManager) installFromOCIRegistry(ctx context.Context, ref ModelRef, progressFn func(MLXDownloadProgress)) error {
+	registryBase := strings.TrimSuffix(ref.Source.BaseURL, "/") + "/v2/" + ref.ResolvedID
+
+	man, err := fetchRemoteManifest(ctx, registryBase+"/manifests/latest", ref.Source.token())
+	if err != nil {
+		return fmt.Errorf("failed to fetch OCI manifest from %s: %w", ref.Source.Name, err)
+	}
+
+	modelPath := m.GetModelPath(ref.Name)
+	if err := os.MkdirAll(modelPath, 0755); err != nil {
+		return fmt.Errorf("failed to create model directory: %w", err)
+	}
+
+	client := &http.Client{}
+	for _, layer := range man.Layers {
+		destPath := filepath.Join(modelPath, layer.Filename)
+		blobURL := registryBase + "/blobs/" + layer.Digest
+		if err := m.downloadFile(ctx, client, blobURL, destPath, layer.Size, func(n int64) {
+			if progressFn != nil {
+				progressFn(MLXDownloadProgress{Filename: layer.Filename, Completed: n, Total: layer.Size})
+			}
+		}); err != nil {
+			os.RemoveAll(modelPath)
+			return fmt.Errorf("failed to pull blob %s: %w", layer.Digest, err)
+		}
+	}
+
+	if ref.Source.Checksum != ChecksumSkip {
+		if err := verifyManifestChecksums(modelPath, man); err != nil {
+			os.RemoveAll(modelPath)
+			return err
+		}
+	}
+
+	return writeManifest(modelPath, man)
+}
+
+// fetchRemoteManifest GETs and decodes a modelManifest from url, optionally
+// authenticating with a bearer token.
+func fetchRemoteManifest(ctx context.Context, url, token string) (*modelManifest, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return nil, fmt.Errorf("server returned %d: %s", resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+
+	var man modelManifest
+	if err := json.NewDecoder(resp.Body).Decode(&man); err != nil {
+		return nil, err
+	}
+	return &man, nil
+}
@@ -0,0 +1,217 @@
+package llm
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// mlx_hfcache.go teaches MLXModelManager to discover and, optionally, write
+// into the cache layout used by huggingface_hub, mlx_lm, and transformers:
+//
+//	$HF_HOME/hub/models--<org>--<repo>/
+//	    blobs/<hash>
+//	    refs/main                     (contains the resolved commit hash)
+//	    snapshots/<commit>/<filename>  (symlinks into blobs/)
+//
+// so a model pulled by one tool is immediately usable by the other.
+
+// hfUseCache reports whether DownloadMLXModel should write directly into the
+// huggingface_hub cache layout instead of ollmlx's own flat directories.
+func hfUseCache() bool {
+	return os.Getenv("OLLMLX_USE_HF_CACHE") == "1"
+}
+
+// hfHubRoot returns the root "hub" directory huggingface_hub reads and
+// writes, honoring the same environment variables it does.
+func hfHubRoot() string {
+	if v := os.Getenv("HUGGINGFACE_HUB_CACHE"); v != "" {
+		return v
+	}
+	if v := os.Getenv("HF_HOME"); v != "" {
+		return filepath.Join(v, "hub")
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return filepath.Join(".cache", "huggingface", "hub")
+	}
+	return filepath.Join(home, ".cache", "huggingface", "hub")
+}
+
+// hfRepoDirName round-trips huggingface_hub's "models--org--repo" directory
+// naming convention, e.g. "mlx-community/Qwen2.5-7B-Instruct-4bit" ->
+// "models--mlx-community--Qwen2.5-7B-Instruct-4bit".
+func hfRepoDirName(modelID string) string {
+	return "models--" + strings.ReplaceAll(modelID, "/", "--")
+}
+
+// hfModelIDFromRepoDir is hfRepoDirName's inverse. Repo IDs are "org/repo",
+// so only the first "--" is treated as the separator; a repo name containing
+// "--" of its own stays intact.
+func hfModelIDFromRepoDir(dirName string) (string, bool) {
+	rest := strings.TrimPrefix(dirName, "models--")
+	if rest == dirName {
+		return "", false
+	}
+	org, repo, ok := strings.Cut(rest, "--")
+	if !ok {
+		return rest, true
+	}
+	return org + "/" + repo, true
+}
+
+// hfCacheRepoDir returns the models--org--repo directory for modelName,
+// without checking whether it actually exists.
+func hfCacheRepoDir(modelName string) (string, bool) {
+	if !strings.Contains(modelName, "/") {
+		return "", false
+	}
+	return filepath.Join(hfHubRoot(), hfRepoDirName(modelName)), true
+}
+
+// hfCacheSnapshotPath resolves modelName's refs/main to a commit hash and
+// returns its snapshots/<commit> directory, if the repo is present in the
+// huggingface_hub cache at all.
+func hfCacheSnapshotPath(modelName string) (string, bool) {
+	repoDir, ok := hfCacheRepoDir(modelName)
+	if !ok {
+		return "", false
+	}
+
+	commit, err := os.ReadFile(filepath.Join(repoDir, "refs", "main"))
+	if err != nil {
+		return "", false
+	}
+
+	snapshotPath := filepath.Join(repoDir, "snapshots", strings.TrimSpace(string(commit)))
+	if _, err := os.Stat(snapshotPath); err != nil {
+		return "", false
+	}
+	return snapshotPath, true
+}
+
+// listHFCacheModels enumerates every models--org--repo directory under the
+// huggingface_hub cache whose refs/main resolves to a snapshot that looks
+// like an MLX model (has config.json and a weights file).
+func (m *MLXModelManager) listHFCacheModels() []MLXModelInfo {
+	entries, err := os.ReadDir(hfHubRoot())
+	if err != nil {
+		return nil
+	}
+
+	var models []MLXModelInfo
+	for _, entry := range entries {
+		if !entry.IsDir() || !strings.HasPrefix(entry.Name(), "models--") {
+			continue
+		}
+
+		modelID, ok := hfModelIDFromRepoDir(entry.Name())
+		if !ok || !m.ModelExists(modelID) {
+			continue
+		}
+
+		info, err := m.GetModelInfo(modelID)
+		if err != nil {
+			continue
+		}
+		info.Name = modelID
+		info.LocalPath = m.GetModelPath(modelID)
+		models = append(models, info)
+	}
+
+	return models
+}
+
+// downloadIntoHFCache implements DownloadMLXModel's OLLMLX_USE_HF_CACHE=1
+// mode: files land in blobs/<sha256> and are exposed through
+// snapshots/<commit>/<filename> symlinks, with refs/main updated last, so a
+// pull done through ollmlx is immediately visible to mlx_lm.generate and
+// vice versa.
+func (m *MLXModelManager) downloadIntoHFCache(ctx context.Context, modelID string, progressFn func(MLXDownloadProgress)) error {
+	repoDir, ok := hfCacheRepoDir(modelID)
+	if !ok {
+		return fmt.Errorf("OLLMLX_USE_HF_CACHE requires an org/repo model id, got %q", modelID)
+	}
+
+	files, sizes, commit, err := m.fetchHFFileList(ctx, modelID)
+	if err != nil {
+		return fmt.Errorf("failed to list files for %s: %w", modelID, err)
+	}
+	if commit == "" {
+		commit = "main"
+	}
+
+	blobsDir := filepath.Join(repoDir, "blobs")
+	snapshotDir := filepath.Join(repoDir, "snapshots", commit)
+	if err := os.MkdirAll(blobsDir, 0755); err != nil {
+		return err
+	}
+	if err := os.MkdirAll(snapshotDir, 0755); err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Join(repoDir, "refs"), 0755); err != nil {
+		return err
+	}
+
+	baseURL := fmt.Sprintf("%s/resolve/main", getMLXBaseURL(modelID))
+	client := &http.Client{Timeout: 30 * time.Minute}
+	token := getHFToken()
+
+	for _, filename := range orderDownloadFiles(files) {
+		fileURL := fmt.Sprintf("%s/%s", baseURL, filename)
+
+		// huggingface_hub names blobs by content hash; we only need ours to
+		// be content-addressed and stable, so sha256 (already our hashing
+		// primitive elsewhere in this package) works just as well here, even
+		// though it isn't bit-for-bit what huggingface_hub itself would pick.
+		tmpDest := filepath.Join(blobsDir, filename+".part")
+		if _, err := m.downloadFileWithSize(ctx, client, fileURL, tmpDest, sizes[filename], func(downloaded, total int64) {
+			if progressFn != nil {
+				progressFn(MLXDownloadProgress{Filename: filename, Completed: downloaded, Total: total, Status: fmt.Sprintf("pulling %s", filename)})
+			}
+		}); err != nil {
+			return fmt.Errorf("failed to download %s: %w", filename, err)
+		}
+
+		digest, err := digestFile(tmpDest)
+		if err != nil {
+			return err
+		}
+		blobPath := filepath.Join(blobsDir, strings.TrimPrefix(digest, "sha256:"))
+		if _, err := os.Stat(blobPath); err != nil {
+			if err := os.Rename(tmpDest, blobPath); err != nil {
+				return fmt.Errorf("failed to store blob for %s: %w", filename, err)
+			}
+		} else {
+			os.Remove(tmpDest)
+		}
+
+		symlinkPath := filepath.Join(snapshotDir, filename)
+		os.Remove(symlinkPath)
+		if err := os.MkdirAll(filepath.Dir(symlinkPath), 0755); err != nil {
+			return err
+		}
+		relTarget, err := filepath.Rel(filepath.Dir(symlinkPath), blobPath)
+		if err != nil {
+			relTarget = blobPath
+		}
+		if err := os.Symlink(relTarget, symlinkPath); err != nil {
+			return fmt.Errorf("failed to link %s into snapshot: %w", filename, err)
+		}
+	}
+
+	if err := os.WriteFile(filepath.Join(repoDir, "refs", "main"), []byte(commit), 0644); err != nil {
+		return fmt.Errorf("failed to update refs/main: %w", err)
+	}
+
+	if progressFn != nil {
+		progressFn(MLXDownloadProgress{Status: "success"})
+	}
+
+	m.refreshOne(filepath.Base(snapshotDir))
+	return nil
+}
@@ -12,6 +12,7 @@ import (
 	"path/filepath"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/ollama/ollama/envconfig"
@@ -34,12 +35,27 @@ type MLXModelInfo struct {
 // MLXModelManager handles MLX model storage and retrieval
 type MLXModelManager struct {
 	modelsDir string
+
+	// chunkIdx maps known chunk digests to their location in the blob
+	// store, rebuilt from on-disk "*.chunks.json" sidecars at startup.
+	chunkIdx *chunkIndex
+
+	// usage backs ListModels/GetModelInfo with a persistent, background-
+	// crawled cache of per-model size/digest info (see mlx_crawler.go).
+	usageMu sync.RWMutex
+	usage   *usageCache
+
+	// crawlerOnce guards StartCrawler so calling it more than once on the
+	// same manager (e.g. from a caller that doesn't track whether it
+	// already started one) doesn't leak a second background goroutine.
+	crawlerOnce sync.Once
 }
 
 // hfModelInfo mirrors the subset of Hugging Face model metadata we need.
 // The "siblings" list contains files at the repository root, which is sufficient
 // for typical MLX model layouts (config, tokenizer, weights shards).
 type hfModelInfo struct {
+	SHA      string `json:"sha"`
 	Siblings []struct {
 		RFilename string `json:"rfilename"`
 		Size      int64  `json:"size"`
@@ -50,14 +66,25 @@ type hfModelInfo struct {
 }
 
 // NewMLXModelManager creates a new MLX model manager
+// NewMLXModelManager is cheap: it only opens modelsDir and loads whatever
+// usage.cache and chunk sidecars are already on disk. It does not start the
+// background usage crawler (see StartCrawler) - call sites that construct a
+// fresh manager per request (most HTTP handlers) would otherwise leak one
+// goroutine per request. Only the one long-lived manager the backend
+// registry builds at startup should call StartCrawler.
 func NewMLXModelManager() *MLXModelManager {
 	// Use ollmlx model directory (defaults to ~/.ollmlx/models)
 	modelsDir := envconfig.Models()
 	os.MkdirAll(modelsDir, 0755)
 
-	return &MLXModelManager{
+	m := &MLXModelManager{
 		modelsDir: modelsDir,
+		chunkIdx:  newChunkIndex(),
+		usage:     loadUsageCache(modelsDir),
 	}
+	m.rebuildChunkIndex()
+
+	return m
 }
 
 // GetModelsDir returns the directory where MLX models are stored
@@ -70,6 +97,7 @@ var internalDirs = map[string]bool{
 	"blobs":     true,
 	"manifests": true,
 	"mlx":       true,
+	"mlx-blobs": true, // content-addressable blob store backing manifest.json models
 	"ollama":    true, // Ollama-format models stored in separate subfolder
 }
 
@@ -127,6 +155,19 @@ func (m *MLXModelManager) ListModels() ([]MLXModelInfo, error) {
 		models = append(models, info)
 	}
 
+	// Mount anything huggingface_hub/mlx_lm/transformers already pulled into
+	// the standard hub cache that isn't already covered above.
+	seen := make(map[string]bool, len(models))
+	for _, info := range models {
+		seen[info.Name] = true
+	}
+	for _, info := range m.listHFCacheModels() {
+		if seen[info.Name] {
+			continue
+		}
+		models = append(models, info)
+	}
+
 	return models, nil
 }
 
@@ -149,21 +190,43 @@ func (m *MLXModelManager) GetModelInfo(modelName string) (MLXModelInfo, error) {
 
 	// Try to read config.json for metadata
 	configPath := filepath.Join(modelPath, "config.json")
+	var config map[string]interface{}
 	if data, err := os.ReadFile(configPath); err == nil {
-		var config map[string]interface{}
 		if err := json.Unmarshal(data, &config); err == nil {
-			// Extract model family and size from config
 			if arch, ok := config["architectures"].([]interface{}); ok && len(arch) > 0 {
 				info.Family = fmt.Sprintf("%v", arch[0])
 			}
-			if hiddenSize, ok := config["hidden_size"].(float64); ok {
-				// Rough estimate of parameter count from hidden size
-				params := int(hiddenSize * 1000 / 1024) // Very rough approximation
-				info.ParameterSize = fmt.Sprintf("%dM", params)
-			}
 		}
 	}
 
+	// Prefer an exact parameter count computed from the safetensors shards'
+	// own tensor shapes; fall back to the old hidden_size-based estimate
+	// for models with no safetensors weights on disk (e.g. npz-only) or
+	// whose shards couldn't be read.
+	if count, err := m.ParameterCount(modelName); err == nil && count > 0 {
+		info.ParameterSize = formatParameterSize(count)
+	} else if hiddenSize, ok := config["hidden_size"].(float64); ok {
+		params := int(hiddenSize * 1000 / 1024) // Very rough approximation
+		info.ParameterSize = fmt.Sprintf("%dM", params)
+	}
+
+	// Prefer the manifest (Docker Registry v2 style layer list) over walking
+	// the directory: it's already an index of every blob this model pulled
+	// in, with no need to stat symlink targets one by one.
+	if manifest, err := readManifest(modelPath); err == nil {
+		info.Size = manifestSize(manifest)
+		info.Digest = manifestDigest(manifest)
+		return info, nil
+	}
+
+	// Next, the background-crawled usage cache: fine as long as it hasn't
+	// gone stale (usageCacheTTL, same as the crawl interval).
+	if entry, fresh := m.lookupUsage(filepath.Base(modelPath)); fresh {
+		info.Size = entry.Size
+		info.Digest = entry.Digest
+		return info, nil
+	}
+
 	// Calculate total size
 	size, err := m.calculateDirSize(modelPath)
 	if err == nil {
@@ -178,6 +241,10 @@ func (m *MLXModelManager) GetModelInfo(modelName string) (MLXModelInfo, error) {
 		info.Digest = fmt.Sprintf("sha256:%x", sum)
 	}
 
+	// We just paid for a live walk; warm the cache so the next call doesn't
+	// have to.
+	go m.refreshOne(filepath.Base(modelPath))
+
 	return info, nil
 }
 
@@ -189,7 +256,18 @@ func (m *MLXModelManager) GetModelPath(modelName string) string {
 	// Convert HuggingFace URL format to local directory name
 	localName := strings.ReplaceAll(modelName, "/", "_")
 
-	return filepath.Join(m.modelsDir, localName)
+	flatPath := filepath.Join(m.modelsDir, localName)
+	if _, err := os.Stat(flatPath); err == nil {
+		return flatPath
+	}
+
+	// Not present in ollmlx's own flat layout; see if huggingface_hub/mlx_lm
+	// already pulled it into the standard hub cache.
+	if snapshotPath, ok := hfCacheSnapshotPath(modelName); ok {
+		return snapshotPath
+	}
+
+	return flatPath
 }
 
 // ModelExists checks if a model is already cached locally
@@ -212,10 +290,43 @@ func (m *MLXModelManager) ModelExists(modelName string) bool {
 	return err1 == nil || err2 == nil
 }
 
-// DeleteModel removes a model from local storage
+// DeleteModel removes a model from local storage. Because model directories
+// are now trees of links into the shared blob store, removing a manifest
+// doesn't free any space by itself, so DeleteModel also sweeps any blob
+// whose refcount across all remaining manifests has dropped to zero.
 func (m *MLXModelManager) DeleteModel(modelName string) error {
 	modelPath := m.GetModelPath(modelName)
-	return os.RemoveAll(modelPath)
+
+	// A path resolved into the huggingface_hub cache is a snapshot, not the
+	// model's own directory: removing just the snapshot would leave the repo's
+	// blobs/ and refs/ behind, still visible to mlx_lm. Remove the whole
+	// models--org--repo directory instead.
+	if repoDir, ok := hfCacheRepoDir(modelName); ok && strings.HasPrefix(modelPath, repoDir) {
+		if err := os.RemoveAll(repoDir); err != nil {
+			return err
+		}
+		m.usageMu.Lock()
+		delete(m.usage.Entries, filepath.Base(modelPath))
+		m.usageMu.Unlock()
+		go m.saveUsageCache()
+		return nil
+	}
+
+	if err := os.RemoveAll(modelPath); err != nil {
+		return err
+	}
+
+	m.usageMu.Lock()
+	delete(m.usage.Entries, filepath.Base(modelPath))
+	m.usageMu.Unlock()
+	go m.saveUsageCache()
+
+	counts, err := m.blobRefCounts()
+	if err != nil {
+		// Model directory is already gone; GC is best-effort from here.
+		return nil
+	}
+	return m.gcUnreferencedBlobs(counts)
 }
 
 // calculateDirSize calculates the total size of a directory
@@ -337,11 +448,11 @@ func getHFToken() string {
 	return strings.TrimSpace(string(data))
 }
 
-func (m *MLXModelManager) fetchHFFileList(ctx context.Context, modelID string) ([]string, map[string]int64, error) {
+func (m *MLXModelManager) fetchHFFileList(ctx context.Context, modelID string) ([]string, map[string]int64, string, error) {
 	url := fmt.Sprintf("https://huggingface.co/api/models/%s", modelID)
 	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
 	if err != nil {
-		return nil, nil, err
+		return nil, nil, "", err
 	}
 
 	// Allow HF tokens from common env vars if provided.
@@ -360,18 +471,18 @@ func (m *MLXModelManager) fetchHFFileList(ctx context.Context, modelID string) (
 
 	resp, err := http.DefaultClient.Do(req)
 	if err != nil {
-		return nil, nil, err
+		return nil, nil, "", err
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
 		body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
-		return nil, nil, fmt.Errorf("huggingface api returned %d: %s", resp.StatusCode, strings.TrimSpace(string(body)))
+		return nil, nil, "", fmt.Errorf("huggingface api returned %d: %s", resp.StatusCode, strings.TrimSpace(string(body)))
 	}
 
 	var meta hfModelInfo
 	if err := json.NewDecoder(resp.Body).Decode(&meta); err != nil {
-		return nil, nil, err
+		return nil, nil, "", err
 	}
 
 	var files []string
@@ -393,10 +504,10 @@ func (m *MLXModelManager) fetchHFFileList(ctx context.Context, modelID string) (
 	}
 
 	if len(files) == 0 {
-		return nil, nil, fmt.Errorf("no downloadable MLX files found for %s", modelID)
+		return nil, nil, "", fmt.Errorf("no downloadable MLX files found for %s", modelID)
 	}
 
-	return files, sizes, nil
+	return files, sizes, meta.SHA, nil
 }
 
 // MLXDownloadProgress represents download progress for a single file
@@ -410,6 +521,10 @@ type MLXDownloadProgress struct {
 // DownloadMLXModel downloads an MLX model from HuggingFace
 // The progress callback receives per-file progress so each file can be tracked separately
 func (m *MLXModelManager) DownloadMLXModel(ctx context.Context, modelID string, progressFn func(MLXDownloadProgress)) error {
+	if hfUseCache() {
+		return m.downloadIntoHFCache(ctx, modelID, progressFn)
+	}
+
 	modelPath := m.GetModelPath(modelID)
 
 	// Create model directory
@@ -424,7 +539,7 @@ func (m *MLXModelManager) DownloadMLXModel(ctx context.Context, modelID string,
 		}
 	}()
 
-	files, sizes, err := m.fetchHFFileList(ctx, modelID)
+	files, sizes, _, err := m.fetchHFFileList(ctx, modelID)
 	if err != nil {
 		// fallback to the legacy file list so we still support minimal layouts
 		files = []string{"config.json", "tokenizer.json", "tokenizer_config.json", "model.safetensors", "weights.npz"}
@@ -432,53 +547,22 @@ func (m *MLXModelManager) DownloadMLXModel(ctx context.Context, modelID string,
 	}
 
 	baseURL := fmt.Sprintf("%s/resolve/main", getMLXBaseURL(modelID))
-	client := &http.Client{Timeout: 30 * time.Minute}
 
-	for _, filename := range files {
-		if err := ctx.Err(); err != nil {
-			return err
-		}
-		fileURL := fmt.Sprintf("%s/%s", baseURL, filename)
-		destPath := filepath.Join(modelPath, filename)
-		expectedSize := sizes[filename]
-
-		// Track if we've discovered the file size
-		var fileTotal int64 = expectedSize
-
-		// Only send initial progress if we already know the size
-		// Otherwise wait until we learn it from Content-Length
-		if progressFn != nil && fileTotal > 0 {
-			progressFn(MLXDownloadProgress{
-				Filename:  filename,
-				Completed: 0,
-				Total:     fileTotal,
-				Status:    fmt.Sprintf("pulling %s", filename),
-			})
-		}
-
-		_, err := m.downloadFileWithSize(ctx, client, fileURL, destPath, expectedSize, func(fileDownloaded int64, discoveredTotal int64) {
-			// Update file total if we discovered it from Content-Length
-			if fileTotal == 0 && discoveredTotal > 0 {
-				slog.Debug("discovered file total", "filename", filename, "discoveredTotal", discoveredTotal)
-				fileTotal = discoveredTotal
-			}
-			// Only report progress if we know the total size (for progress bar display)
-			if progressFn != nil && fileTotal > 0 {
-				progressFn(MLXDownloadProgress{
-					Filename:  filename,
-					Completed: fileDownloaded,
-					Total:     fileTotal,
-					Status:    fmt.Sprintf("pulling %s", filename),
-				})
-			}
-		})
+	// Files are pulled concurrently (OLLMLX_DOWNLOAD_CONCURRENCY workers,
+	// default 4) through a shared puller state so progressFn still sees one
+	// coherent, serialized stream of per-file updates even though several
+	// shards are in flight at once. Each file lands in the content-
+	// addressable blob store and is linked into modelPath under its own
+	// name, so a shard already present for a sibling model is never
+	// downloaded twice.
+	layers, err := m.downloadFilesConcurrently(ctx, baseURL, modelPath, files, sizes, progressFn)
+	if err != nil {
+		return err
+	}
 
-		if err != nil {
-			if err := ctx.Err(); err != nil {
-				return err
-			}
-			return fmt.Errorf("failed to download %s: %w", filename, err)
-		}
+	manifest := &modelManifest{SchemaVersion: 2, Layers: layers}
+	if err := writeManifest(modelPath, manifest); err != nil {
+		return fmt.Errorf("failed to write manifest: %w", err)
 	}
 
 	if progressFn != nil {
@@ -489,15 +573,16 @@ func (m *MLXModelManager) DownloadMLXModel(ctx context.Context, modelID string,
 
 	cleanup = false
 
-	// Compute a lightweight digest for listing/show calls.
-	if digest, err := computeDigest(modelPath); err == nil {
-		if progressFn != nil {
-			progressFn(MLXDownloadProgress{
-				Status: fmt.Sprintf("digest %s", digest),
-			})
-		}
+	if progressFn != nil {
+		progressFn(MLXDownloadProgress{
+			Status: fmt.Sprintf("digest %s", manifestDigest(manifest)),
+		})
 	}
 
+	// Warm usage.cache immediately so a ListModels/GetModelInfo call right
+	// after the pull completes doesn't pay for a live walk.
+	m.refreshOne(filepath.Base(modelPath))
+
 	return nil
 }
 
@@ -0,0 +1,202 @@
+package llm
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// mlx_paramcount.go computes an exact parameter count for a cached MLX model
+// by summing tensor shapes out of its safetensors shards, instead of the old
+// hidden_size-from-config.json guess. The result is cached in a "params.json"
+// sidecar next to the model so repeated GetModelInfo/ParameterCount calls
+// (list, show) don't re-read every shard's header.
+
+// paramsCacheFile is the on-disk shape of the params.json sidecar.
+type paramsCacheFile struct {
+	ParameterCount int64 `json:"parameter_count"`
+}
+
+// safetensorsTensorHeader is the per-tensor entry in a safetensors file's
+// JSON header; "__metadata__" (which has no "shape") is skipped by callers.
+type safetensorsTensorHeader struct {
+	Shape []int64 `json:"shape"`
+}
+
+// ParameterCount returns the exact number of parameters in the model cached
+// under localName, computed by summing tensor shapes from its safetensors
+// shards. The first call for a model pays for reading every shard's header;
+// the result is cached in a params.json sidecar so later calls are O(1).
+func (m *MLXModelManager) ParameterCount(localName string) (int64, error) {
+	modelPath := m.GetModelPath(localName)
+
+	if count, ok := readParamsCache(modelPath); ok {
+		return count, nil
+	}
+
+	count, err := countSafetensorsParameters(modelPath)
+	if err != nil {
+		return 0, err
+	}
+
+	if err := writeParamsCache(modelPath, count); err != nil {
+		slog.Warn("failed to cache parameter count", "model", localName, "error", err)
+	}
+
+	return count, nil
+}
+
+// formatParameterSize renders a parameter count the way HuggingFace model
+// cards do: a magnitude suffix (K/M/B/T) with at most one decimal place,
+// e.g. 7_000_000_000 -> "7B", 1_700_000_000 -> "1.7B".
+func formatParameterSize(count int64) string {
+	switch {
+	case count >= 1_000_000_000_000:
+		return trimParameterDecimal(float64(count) / 1_000_000_000_000) + "T"
+	case count >= 1_000_000_000:
+		return trimParameterDecimal(float64(count) / 1_000_000_000) + "B"
+	case count >= 1_000_000:
+		return trimParameterDecimal(float64(count) / 1_000_000) + "M"
+	case count >= 1_000:
+		return trimParameterDecimal(float64(count) / 1_000) + "K"
+	default:
+		return strconv.FormatInt(count, 10)
+	}
+}
+
+// trimParameterDecimal formats f with one decimal place, dropping it when
+// it's a trailing ".0".
+func trimParameterDecimal(f float64) string {
+	s := strconv.FormatFloat(f, 'f', 1, 64)
+	return strings.TrimSuffix(s, ".0")
+}
+
+// readParamsCache reads a previously cached parameter count, if any.
+func readParamsCache(modelPath string) (int64, bool) {
+	data, err := os.ReadFile(filepath.Join(modelPath, "params.json"))
+	if err != nil {
+		return 0, false
+	}
+	var cache paramsCacheFile
+	if err := json.Unmarshal(data, &cache); err != nil {
+		return 0, false
+	}
+	return cache.ParameterCount, true
+}
+
+// writeParamsCache persists count to modelPath's params.json sidecar.
+func writeParamsCache(modelPath string, count int64) error {
+	data, err := json.Marshal(paramsCacheFile{ParameterCount: count})
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(modelPath, "params.json"), data, 0644)
+}
+
+// countSafetensorsParameters sums the element count of every tensor across
+// every safetensors shard backing modelPath.
+func countSafetensorsParameters(modelPath string) (int64, error) {
+	shards, err := safetensorsShardFiles(modelPath)
+	if err != nil {
+		return 0, err
+	}
+	if len(shards) == 0 {
+		return 0, fmt.Errorf("no safetensors shards found in %s", modelPath)
+	}
+
+	var total int64
+	for _, shard := range shards {
+		n, err := sumSafetensorsHeaderParams(filepath.Join(modelPath, shard))
+		if err != nil {
+			return 0, fmt.Errorf("failed to read %s: %w", shard, err)
+		}
+		total += n
+	}
+	return total, nil
+}
+
+// safetensorsShardFiles returns the unique safetensors filenames backing
+// modelPath: every file named in model.safetensors.index.json's weight_map,
+// or the single model.safetensors file for models with no index.
+func safetensorsShardFiles(modelPath string) ([]string, error) {
+	indexPath := filepath.Join(modelPath, "model.safetensors.index.json")
+	data, err := os.ReadFile(indexPath)
+	if err == nil {
+		var index struct {
+			WeightMap map[string]string `json:"weight_map"`
+		}
+		if err := json.Unmarshal(data, &index); err != nil {
+			return nil, fmt.Errorf("failed to parse %s: %w", indexPath, err)
+		}
+
+		seen := make(map[string]bool, len(index.WeightMap))
+		var files []string
+		for _, f := range index.WeightMap {
+			if !seen[f] {
+				seen[f] = true
+				files = append(files, f)
+			}
+		}
+		sort.Strings(files)
+		return files, nil
+	}
+	if !os.IsNotExist(err) {
+		return nil, err
+	}
+
+	if _, err := os.Stat(filepath.Join(modelPath, "model.safetensors")); err == nil {
+		return []string{"model.safetensors"}, nil
+	}
+	return nil, nil
+}
+
+// sumSafetensorsHeaderParams reads only the JSON header of a safetensors
+// file — an 8-byte little-endian length prefix, then that many bytes of
+// header JSON — and sums the element count of every tensor's shape. It
+// never reads the tensor data itself.
+func sumSafetensorsHeaderParams(path string) (int64, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+
+	var headerLen uint64
+	if err := binary.Read(f, binary.LittleEndian, &headerLen); err != nil {
+		return 0, fmt.Errorf("failed to read header length: %w", err)
+	}
+
+	header := make([]byte, headerLen)
+	if _, err := io.ReadFull(f, header); err != nil {
+		return 0, fmt.Errorf("failed to read header: %w", err)
+	}
+
+	var tensors map[string]json.RawMessage
+	if err := json.Unmarshal(header, &tensors); err != nil {
+		return 0, fmt.Errorf("failed to parse header: %w", err)
+	}
+
+	var total int64
+	for name, raw := range tensors {
+		if name == "__metadata__" {
+			continue
+		}
+		var tensor safetensorsTensorHeader
+		if err := json.Unmarshal(raw, &tensor); err != nil {
+			return 0, fmt.Errorf("failed to parse tensor %q: %w", name, err)
+		}
+		params := int64(1)
+		for _, dim := range tensor.Shape {
+			params *= dim
+		}
+		total += params
+	}
+	return total, nil
+}
@@ -0,0 +1,310 @@
+package llm
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// downloadConcurrency returns the number of files that may be pulled in
+// parallel, configurable via OLLMLX_DOWNLOAD_CONCURRENCY (default 4).
+func downloadConcurrency() int {
+	if v := os.Getenv("OLLMLX_DOWNLOAD_CONCURRENCY"); v != "" {
+		if n, err := strconv.Atoi(v); err == nil && n > 0 {
+			return n
+		}
+	}
+	return 4
+}
+
+// sharedPullerState is the coordination point for a single DownloadMLXModel
+// call. Every worker pulling a file for this download reports into the same
+// state, which lets one dedicated goroutine synthesize coherent, serialized
+// MLXDownloadProgress updates instead of racing the caller's progressFn.
+// This mirrors syncthing's sharedPullerState: per-file counters plus an
+// aggregate counter, all behind one mutex, with a slot for the first error.
+type sharedPullerState struct {
+	mu sync.Mutex
+
+	completed map[string]int64 // filename -> bytes completed
+	total     map[string]int64 // filename -> total bytes (0 until discovered)
+	aggregate int64            // bytes completed across all files
+
+	firstErr     error
+	firstErrFile string
+
+	updates chan MLXDownloadProgress
+}
+
+func newSharedPullerState(files []string, sizes map[string]int64) *sharedPullerState {
+	s := &sharedPullerState{
+		completed: make(map[string]int64, len(files)),
+		total:     make(map[string]int64, len(files)),
+		updates:   make(chan MLXDownloadProgress, 64),
+	}
+	for _, f := range files {
+		s.total[f] = sizes[f]
+	}
+	return s
+}
+
+// report records progress for filename and emits an update for the
+// serializing goroutine to forward to the caller's progressFn.
+func (s *sharedPullerState) report(filename string, downloaded, discoveredTotal int64) {
+	s.mu.Lock()
+	prev := s.completed[filename]
+	if discoveredTotal > 0 && s.total[filename] == 0 {
+		s.total[filename] = discoveredTotal
+	}
+	s.completed[filename] = downloaded
+	s.aggregate += downloaded - prev
+	total := s.total[filename]
+	s.mu.Unlock()
+
+	if total <= 0 {
+		return
+	}
+
+	s.updates <- MLXDownloadProgress{
+		Filename:  filename,
+		Completed: downloaded,
+		Total:     total,
+		Status:    fmt.Sprintf("pulling %s", filename),
+	}
+}
+
+// fail records the first error seen by any worker. Subsequent errors are
+// dropped; the first one is what gets returned to the caller.
+func (s *sharedPullerState) fail(filename string, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.firstErr == nil {
+		s.firstErr = err
+		s.firstErrFile = filename
+	}
+}
+
+func (s *sharedPullerState) err() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.firstErr
+}
+
+// aggregateLine synthesizes a single "downloading N files" style progress
+// frame from the current totals, for the serializing goroutine to emit
+// alongside the per-file updates.
+func (s *sharedPullerState) aggregateLine() MLXDownloadProgress {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var completed, total int64
+	for f, t := range s.total {
+		completed += s.completed[f]
+		total += t
+	}
+
+	return MLXDownloadProgress{
+		Filename:  "",
+		Completed: completed,
+		Total:     total,
+		Status:    "downloading",
+	}
+}
+
+// orderDownloadFiles schedules small metadata/config/tokenizer files first so
+// an early auth or 404 failure surfaces fast, before workers start filling
+// up on multi-gigabyte safetensors shards.
+func orderDownloadFiles(files []string) []string {
+	ordered := make([]string, len(files))
+	copy(ordered, files)
+
+	priority := func(name string) int {
+		if !shouldDownloadFile(name) {
+			return 2
+		}
+		if hasSuffixAny(name, ".safetensors", ".npz") || hasPrefixSuffix(name, "model-", ".safetensors") {
+			return 1
+		}
+		return 0 // config/tokenizer/metadata
+	}
+
+	sort.SliceStable(ordered, func(i, j int) bool {
+		return priority(ordered[i]) < priority(ordered[j])
+	})
+
+	return ordered
+}
+
+func hasSuffixAny(name string, suffixes ...string) bool {
+	for _, suf := range suffixes {
+		if len(name) >= len(suf) && name[len(name)-len(suf):] == suf {
+			return true
+		}
+	}
+	return false
+}
+
+func hasPrefixSuffix(name, prefix, suffix string) bool {
+	return len(name) >= len(prefix) && name[:len(prefix)] == prefix && hasSuffixAny(name, suffix)
+}
+
+// downloadFilesConcurrently pulls files for modelID into the content-
+// addressable blob store using a bounded worker pool, reporting progress
+// through a sharedPullerState so the caller still sees one coherent,
+// serialized stream of MLXDownloadProgress values. Cancellation is
+// cooperative: once ctx is canceled or a sibling worker has failed, workers
+// stop picking up new files, but any in-flight blob's ".part" file is left
+// in place under blobsTmpDir so downloadFileWithSize can resume it later.
+// On success it returns one blobDescriptor per file, ready to go into the
+// model's manifest.json.
+func (m *MLXModelManager) downloadFilesConcurrently(ctx context.Context, baseURL, modelPath string, files []string, sizes map[string]int64, progressFn func(MLXDownloadProgress)) ([]blobDescriptor, error) {
+	files = orderDownloadFiles(files)
+	state := newSharedPullerState(files, sizes)
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var relayWG sync.WaitGroup
+	if progressFn != nil {
+		relayWG.Add(1)
+		go func() {
+			defer relayWG.Done()
+			for update := range state.updates {
+				progressFn(update)
+			}
+			progressFn(state.aggregateLine())
+		}()
+	}
+
+	client := &http.Client{Timeout: 30 * time.Minute}
+	token := getHFToken()
+
+	if err := os.MkdirAll(m.blobsTmpDirFor(modelPath), 0755); err != nil {
+		close(state.updates)
+		relayWG.Wait()
+		return nil, err
+	}
+
+	jobs := make(chan string)
+	var wg sync.WaitGroup
+	concurrency := downloadConcurrency()
+	if concurrency > len(files) {
+		concurrency = len(files)
+	}
+	if concurrency < 1 {
+		concurrency = 1
+	}
+
+	var descMu sync.Mutex
+	descByFile := make(map[string]blobDescriptor, len(files))
+
+	for i := 0; i < concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for filename := range jobs {
+				if ctx.Err() != nil || state.err() != nil {
+					return
+				}
+
+				desc, err := m.fetchFileIntoBlobStore(ctx, client, token, baseURL, modelPath, filename, sizes[filename], func(downloaded, discoveredTotal int64) {
+					state.report(filename, downloaded, discoveredTotal)
+				})
+				if err != nil {
+					state.fail(filename, fmt.Errorf("failed to download %s: %w", filename, err))
+					cancel()
+					return
+				}
+
+				if err := m.linkBlobIntoModel(modelPath, desc); err != nil {
+					state.fail(filename, fmt.Errorf("failed to link %s: %w", filename, err))
+					cancel()
+					return
+				}
+
+				descMu.Lock()
+				descByFile[filename] = desc
+				descMu.Unlock()
+			}
+		}()
+	}
+
+	for _, filename := range files {
+		select {
+		case jobs <- filename:
+		case <-ctx.Done():
+		}
+	}
+	close(jobs)
+
+	wg.Wait()
+	close(state.updates)
+	relayWG.Wait()
+
+	if err := state.err(); err != nil {
+		return nil, err
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	descriptors := make([]blobDescriptor, 0, len(files))
+	for _, filename := range files {
+		descriptors = append(descriptors, descByFile[filename])
+	}
+	return descriptors, nil
+}
+
+// fetchFileIntoBlobStore resolves a single file's content digest, either by
+// a cheap HEAD request (X-Linked-ETag) or, failing that, by downloading the
+// file and hashing it, and ensures it ends up stored under blobsRoot keyed
+// by that digest.
+func (m *MLXModelManager) fetchFileIntoBlobStore(ctx context.Context, client *http.Client, token, baseURL, modelPath, filename string, expectedSize int64, progress func(int64, int64)) (blobDescriptor, error) {
+	fileURL := fmt.Sprintf("%s/%s", baseURL, filename)
+
+	if digest := remoteBlobDigest(ctx, client, fileURL, token); digest != "" {
+		if stat, err := os.Stat(m.blobPath(digest)); err == nil {
+			// Already have this exact content; skip the download entirely.
+			progress(stat.Size(), stat.Size())
+			return blobDescriptor{
+				Filename:  filename,
+				MediaType: mediaTypeFor(filename),
+				Size:      stat.Size(),
+				Digest:    digest,
+			}, nil
+		}
+	}
+
+	tmpDest := filepath.Join(m.blobsTmpDirFor(modelPath), filename)
+
+	// When chunked pulls are enabled, try to assemble the file from bytes
+	// we already hold plus ranged fetches for what's missing before falling
+	// back to a plain whole-file download.
+	if used, err := m.downloadFileChunked(ctx, client, token, fileURL, tmpDest, progress); err != nil {
+		return blobDescriptor{}, err
+	} else if used {
+		desc, err := m.storeBlobFromFile(tmpDest, filename)
+		if err != nil {
+			return blobDescriptor{}, err
+		}
+		m.ensureChunkSidecar(desc)
+		return desc, nil
+	}
+
+	if _, err := m.downloadFileWithSize(ctx, client, fileURL, tmpDest, expectedSize, progress); err != nil {
+		return blobDescriptor{}, err
+	}
+
+	desc, err := m.storeBlobFromFile(tmpDest, filename)
+	if err != nil {
+		return blobDescriptor{}, err
+	}
+	m.ensureChunkSidecar(desc)
+	return desc, nil
+}
@@ -0,0 +1,313 @@
+package llm
+
+import (
+	"container/list"
+	"context"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/ollama/ollama/envconfig"
+	"github.com/ollama/ollama/runner/mlxrunner"
+)
+
+// MLXRunnerPool keeps one warm mlxrunner.Server subprocess per model path
+// alive across requests, instead of every caller spawning and tearing one
+// down per call. A runner is started (and loaded) on first Acquire for its
+// model path, reused by later Acquire calls for the same path, and evicted
+// either to make room under maxLoaded or after sitting idle past
+// envconfig.KeepAlive(), mirroring how Ollama itself unloads GGUF runners.
+// Acquire also notices a runner whose subprocess crashed on its own and
+// transparently starts a fresh one in its place.
+type MLXRunnerPool struct {
+	mu        sync.Mutex
+	entries   map[string]*pooledRunner
+	lru       *list.List // of *pooledRunner; most-recently-used at the front
+	maxLoaded int
+
+	// startFunc is indirected for tests; production code should never
+	// reassign it.
+	startFunc func(ctx context.Context, modelPath string) (*mlxrunner.Server, error)
+
+	// OnFreshRunner, if set, is called after Acquire starts a genuinely new
+	// backend subprocess for modelPath - on first use, or after a crashed
+	// one is replaced - but not when Acquire simply reuses an already-warm
+	// runner. ollmlx serve wires this to llm/mlxcontrol.Controller's
+	// TriggerReconcile, so the desired-state reconciler re-converges
+	// whenever a runner "reconnects". Set it only once (ollmlx serve does so
+	// in defaultBackendRegistry, after NewMLXRunnerPool has built the pool);
+	// production code should never reassign it afterward.
+	OnFreshRunner func(modelPath string)
+}
+
+type pooledRunner struct {
+	modelPath string
+	server    *mlxrunner.Server
+	refCount  int
+	lastUsed  time.Time
+	elem      *list.Element
+}
+
+// RunnerHandle is a leased reference to a pooled runner. Callers must call
+// Release when they're done with it so the pool can track when the runner
+// becomes idle; Release never terminates the subprocess itself.
+type RunnerHandle struct {
+	pool  *MLXRunnerPool
+	entry *pooledRunner
+}
+
+// Server returns the gRPC-backed runner this handle leases.
+func (h *RunnerHandle) Server() *mlxrunner.Server { return h.entry.server }
+
+// Release returns the handle to the pool. The underlying subprocess stays
+// warm until it's been idle for envconfig.KeepAlive() or is evicted to make
+// room for another model.
+func (h *RunnerHandle) Release() {
+	h.pool.release(h.entry)
+}
+
+// NewMLXRunnerPool constructs a pool that keeps at most maxLoaded models'
+// runners warm at once, and starts its background idle-eviction sweep
+// (unlike NewMLXModelManager, which leaves its usage-cache crawler to
+// StartCrawler).
+func NewMLXRunnerPool(maxLoaded int) *MLXRunnerPool {
+	p := &MLXRunnerPool{
+		entries:   make(map[string]*pooledRunner),
+		lru:       list.New(),
+		maxLoaded: maxLoaded,
+		startFunc: startPooledMLXRunner,
+	}
+	p.startIdleSweep()
+	return p
+}
+
+// idleSweepInterval controls how often the pool checks for runners that
+// have sat unleased past envconfig.KeepAlive(), overridable via
+// OLLMLX_IDLE_SWEEP_INTERVAL for testing.
+func idleSweepInterval() time.Duration {
+	if v := os.Getenv("OLLMLX_IDLE_SWEEP_INTERVAL"); v != "" {
+		if d, err := time.ParseDuration(v); err == nil && d > 0 {
+			return d
+		}
+	}
+	return time.Minute
+}
+
+// startPooledMLXRunner starts the Python MLX backend for modelPath, waits
+// for it to report healthy by polling Health instead of sleeping a fixed
+// duration, and loads modelPath into it.
+func startPooledMLXRunner(ctx context.Context, modelPath string) (*mlxrunner.Server, error) {
+	rs := mlxrunner.NewServer(modelPath)
+	if err := rs.Start(ctx); err != nil {
+		return nil, fmt.Errorf("failed to start mlx runner: %w", err)
+	}
+
+	if err := waitForRunnerHealth(ctx, rs); err != nil {
+		rs.Close()
+		return nil, err
+	}
+
+	if err := rs.Load(ctx, modelPath); err != nil {
+		rs.Close()
+		return nil, err
+	}
+
+	return rs, nil
+}
+
+// waitForRunnerHealth polls rs until it reports healthy or ctx is done.
+func waitForRunnerHealth(ctx context.Context, rs *mlxrunner.Server) error {
+	var lastErr error
+	for {
+		if err := rs.Health(ctx); err == nil {
+			return nil
+		} else {
+			lastErr = err
+		}
+
+		select {
+		case <-ctx.Done():
+			if lastErr != nil {
+				return fmt.Errorf("mlx runner did not become healthy: %w", lastErr)
+			}
+			return ctx.Err()
+		case <-time.After(100 * time.Millisecond):
+		}
+	}
+}
+
+// Acquire returns a warm runner for modelPath, starting one if none is
+// already running. If the pool is already at maxLoaded, the
+// least-recently-used idle runner is evicted first to make room; if every
+// loaded runner is currently leased, Acquire returns an error rather than
+// exceeding maxLoaded. Callers must call Release on the returned handle
+// when done with it.
+func (p *MLXRunnerPool) Acquire(ctx context.Context, modelPath string) (*RunnerHandle, error) {
+	p.mu.Lock()
+	if entry, ok := p.entries[modelPath]; ok {
+		if !crashed(entry.server) {
+			p.touchLocked(entry)
+			p.mu.Unlock()
+			return &RunnerHandle{pool: p, entry: entry}, nil
+		}
+		// The subprocess died on its own (OOM, segfault in the MLX
+		// backend, etc.) rather than being evicted by us. Drop it now so
+		// the startFunc call below replaces it instead of every future
+		// Acquire reusing a dead connection forever.
+		p.lru.Remove(entry.elem)
+		delete(p.entries, modelPath)
+		p.mu.Unlock()
+		entry.server.Close()
+		p.mu.Lock()
+	}
+
+	var evicted *mlxrunner.Server
+	if len(p.entries) >= p.maxLoaded {
+		victim, err := p.evictOneLocked()
+		if err != nil {
+			p.mu.Unlock()
+			return nil, err
+		}
+		evicted = victim
+	}
+	p.mu.Unlock()
+
+	if evicted != nil {
+		evicted.Close()
+	}
+
+	server, err := p.startFunc(ctx, modelPath)
+	if err != nil {
+		return nil, err
+	}
+
+	p.mu.Lock()
+	// Another caller may have raced us to start the same model while we
+	// didn't hold p.mu; prefer whichever runner won and shut ours down
+	// rather than leaking a duplicate subprocess.
+	if entry, ok := p.entries[modelPath]; ok {
+		p.touchLocked(entry)
+		p.mu.Unlock()
+		server.Close()
+		return &RunnerHandle{pool: p, entry: entry}, nil
+	}
+
+	entry := &pooledRunner{modelPath: modelPath, server: server, refCount: 1, lastUsed: time.Now()}
+	entry.elem = p.lru.PushFront(entry)
+	p.entries[modelPath] = entry
+	p.mu.Unlock()
+
+	if p.OnFreshRunner != nil {
+		p.OnFreshRunner(modelPath)
+	}
+
+	return &RunnerHandle{pool: p, entry: entry}, nil
+}
+
+// crashed reports whether server's backend subprocess has already exited,
+// without blocking if it hasn't.
+func crashed(server *mlxrunner.Server) bool {
+	select {
+	case <-server.Exited():
+		return true
+	default:
+		return false
+	}
+}
+
+// touchLocked marks entry as just-used. p.mu must be held.
+func (p *MLXRunnerPool) touchLocked(entry *pooledRunner) {
+	entry.refCount++
+	entry.lastUsed = time.Now()
+	p.lru.MoveToFront(entry.elem)
+}
+
+// evictOneLocked removes the least-recently-used idle entry and returns
+// its server for the caller to Close() once p.mu is released. p.mu must be
+// held. Returns an error if every loaded runner is currently leased.
+func (p *MLXRunnerPool) evictOneLocked() (*mlxrunner.Server, error) {
+	for e := p.lru.Back(); e != nil; e = e.Prev() {
+		entry := e.Value.(*pooledRunner)
+		if entry.refCount == 0 {
+			p.lru.Remove(e)
+			delete(p.entries, entry.modelPath)
+			return entry.server, nil
+		}
+	}
+	return nil, fmt.Errorf("mlx runner pool: all %d loaded models are in use", p.maxLoaded)
+}
+
+// release decrements entry's lease count. p.mu is not assumed held.
+func (p *MLXRunnerPool) release(entry *pooledRunner) {
+	p.mu.Lock()
+	entry.refCount--
+	entry.lastUsed = time.Now()
+	p.mu.Unlock()
+}
+
+// startIdleSweep launches the single background goroutine that evicts
+// runners sitting unleased for longer than envconfig.KeepAlive(), once per
+// idleSweepInterval, for the life of the process.
+func (p *MLXRunnerPool) startIdleSweep() {
+	go func() {
+		ticker := time.NewTicker(idleSweepInterval())
+		defer ticker.Stop()
+		for range ticker.C {
+			p.sweepIdle()
+		}
+	}()
+}
+
+func (p *MLXRunnerPool) sweepIdle() {
+	keepAlive := envconfig.KeepAlive()
+
+	p.mu.Lock()
+	now := time.Now()
+	var stale []*mlxrunner.Server
+	for e := p.lru.Back(); e != nil; {
+		prev := e.Prev()
+		entry := e.Value.(*pooledRunner)
+		if entry.refCount == 0 && now.Sub(entry.lastUsed) >= keepAlive {
+			p.lru.Remove(e)
+			delete(p.entries, entry.modelPath)
+			stale = append(stale, entry.server)
+		}
+		e = prev
+	}
+	p.mu.Unlock()
+
+	for _, s := range stale {
+		s.Close()
+	}
+}
+
+// Embeddings returns one embedding vector per entry in input, computed by
+// a warm runner for modelPath, starting and loading one first if none is
+// already running (the same as Acquire).
+func (p *MLXRunnerPool) Embeddings(ctx context.Context, modelPath string, input []string) ([][]float32, error) {
+	handle, err := p.Acquire(ctx, modelPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to acquire mlx runner: %w", err)
+	}
+	defer handle.Release()
+
+	return handle.Server().Embed(ctx, input)
+}
+
+// Close terminates every runner the pool currently holds, regardless of
+// whether it's leased. Intended for process shutdown only.
+func (p *MLXRunnerPool) Close() {
+	p.mu.Lock()
+	servers := make([]*mlxrunner.Server, 0, len(p.entries))
+	for _, entry := range p.entries {
+		servers = append(servers, entry.server)
+	}
+	p.entries = make(map[string]*pooledRunner)
+	p.lru.Init()
+	p.mu.Unlock()
+
+	for _, s := range servers {
+		s.Close()
+	}
+}
@@ -0,0 +1,148 @@
+package llm
+
+import (
+	"container/list"
+	"context"
+	"testing"
+
+	"github.com/ollama/ollama/runner/mlxrunner"
+)
+
+// newTestPool builds a pool whose startFunc hands out bare, never-started
+// mlxrunner.Server values instead of spawning real subprocesses. A
+// never-started Server's Close is a safe no-op (its *exec.Cmd is nil) and it
+// never reports crashed (its Exited channel is never closed), which is all
+// these tests need to exercise the pool's own bookkeeping.
+func newTestPool(maxLoaded int) *MLXRunnerPool {
+	return &MLXRunnerPool{
+		entries:   make(map[string]*pooledRunner),
+		lru:       list.New(),
+		maxLoaded: maxLoaded,
+		startFunc: func(ctx context.Context, modelPath string) (*mlxrunner.Server, error) {
+			return mlxrunner.NewServer(modelPath), nil
+		},
+	}
+}
+
+// TestMLXRunnerPoolAcquireReusesWarmRunner verifies a second Acquire for the
+// same model path returns the already-running runner instead of starting
+// another one.
+func TestMLXRunnerPoolAcquireReusesWarmRunner(t *testing.T) {
+	p := newTestPool(2)
+
+	h1, err := p.Acquire(context.Background(), "model-a")
+	if err != nil {
+		t.Fatalf("first Acquire failed: %v", err)
+	}
+	h1.Release()
+
+	h2, err := p.Acquire(context.Background(), "model-a")
+	if err != nil {
+		t.Fatalf("second Acquire failed: %v", err)
+	}
+	defer h2.Release()
+
+	if h1.Server() != h2.Server() {
+		t.Error("expected the second Acquire to reuse the first runner")
+	}
+}
+
+// TestMLXRunnerPoolEvictsLeastRecentlyUsed verifies that when maxLoaded is
+// reached, Acquire evicts the idle runner that was used longest ago rather
+// than an arbitrary one.
+func TestMLXRunnerPoolEvictsLeastRecentlyUsed(t *testing.T) {
+	p := newTestPool(2)
+	ctx := context.Background()
+
+	ha, err := p.Acquire(ctx, "model-a")
+	if err != nil {
+		t.Fatalf("Acquire model-a failed: %v", err)
+	}
+	ha.Release()
+
+	hb, err := p.Acquire(ctx, "model-b")
+	if err != nil {
+		t.Fatalf("Acquire model-b failed: %v", err)
+	}
+	hb.Release()
+
+	// model-a is now the least recently used of the two. Acquiring a third,
+	// distinct model should evict it rather than model-b.
+	hc, err := p.Acquire(ctx, "model-c")
+	if err != nil {
+		t.Fatalf("Acquire model-c failed: %v", err)
+	}
+	defer hc.Release()
+
+	if _, ok := p.entries["model-a"]; ok {
+		t.Error("expected model-a to be evicted as the least recently used entry")
+	}
+	if _, ok := p.entries["model-b"]; !ok {
+		t.Error("expected model-b to survive eviction")
+	}
+}
+
+// TestMLXRunnerPoolRefusesEvictionWhenAllLeased verifies Acquire returns an
+// error rather than exceeding maxLoaded when every already-loaded runner is
+// currently leased.
+func TestMLXRunnerPoolRefusesEvictionWhenAllLeased(t *testing.T) {
+	p := newTestPool(1)
+	ctx := context.Background()
+
+	h, err := p.Acquire(ctx, "model-a")
+	if err != nil {
+		t.Fatalf("Acquire model-a failed: %v", err)
+	}
+	defer h.Release()
+
+	if _, err := p.Acquire(ctx, "model-b"); err == nil {
+		t.Fatal("expected Acquire to fail when the only loaded runner is leased")
+	}
+}
+
+// TestMLXRunnerPoolReleaseAllowsLaterEviction verifies that releasing a
+// runner's lease makes it eligible for eviction by a later Acquire.
+func TestMLXRunnerPoolReleaseAllowsLaterEviction(t *testing.T) {
+	p := newTestPool(1)
+	ctx := context.Background()
+
+	h, err := p.Acquire(ctx, "model-a")
+	if err != nil {
+		t.Fatalf("Acquire model-a failed: %v", err)
+	}
+	h.Release()
+
+	if _, err := p.Acquire(ctx, "model-b"); err != nil {
+		t.Fatalf("expected Acquire to succeed once model-a was released: %v", err)
+	}
+	if _, ok := p.entries["model-a"]; ok {
+		t.Error("expected model-a to be evicted after model-b was acquired")
+	}
+}
+
+// TestMLXRunnerPoolOnFreshRunnerFiresOnlyForNewSubprocess verifies
+// OnFreshRunner is called when Acquire starts a genuinely new subprocess but
+// not when it simply reuses an already-warm one.
+func TestMLXRunnerPoolOnFreshRunnerFiresOnlyForNewSubprocess(t *testing.T) {
+	p := newTestPool(2)
+	ctx := context.Background()
+
+	var fresh []string
+	p.OnFreshRunner = func(modelPath string) { fresh = append(fresh, modelPath) }
+
+	h1, err := p.Acquire(ctx, "model-a")
+	if err != nil {
+		t.Fatalf("first Acquire failed: %v", err)
+	}
+	h1.Release()
+
+	h2, err := p.Acquire(ctx, "model-a")
+	if err != nil {
+		t.Fatalf("second Acquire failed: %v", err)
+	}
+	h2.Release()
+
+	if len(fresh) != 1 || fresh[0] != "model-a" {
+		t.Errorf("OnFreshRunner calls = %v, want exactly one call for model-a", fresh)
+	}
+}
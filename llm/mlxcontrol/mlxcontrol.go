@@ -0,0 +1,258 @@
+// Package mlxcontrol is the control plane that keeps the set of locally
+// cached MLX models converged on a declared desired state, instead of
+// every pull/delete being a one-off operator action. A Controller persists
+// the desired state as models.json alongside the model cache, and its
+// Reconcile diffs that against llm.MLXModelManager.ListModels to pull
+// whatever's missing and evict whatever's no longer wanted, emitting
+// Events along the way so callers (a CLI progress bar, the /api/mlx/state
+// handler, tests) can observe convergence instead of only its end result.
+package mlxcontrol
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/ollama/ollama/llm"
+)
+
+// EventKind identifies what happened during a Reconcile pass.
+type EventKind string
+
+const (
+	// ModelLoading is emitted just before a desired-but-missing model
+	// starts pulling.
+	ModelLoading EventKind = "ModelLoading"
+	// ModelReady is emitted once a pulled model is confirmed present.
+	ModelReady EventKind = "ModelReady"
+	// ModelEvicted is emitted after a no-longer-desired, unpinned model is
+	// deleted from local storage.
+	ModelEvicted EventKind = "ModelEvicted"
+	// ReconcileFailed is emitted for a model whose pull or eviction failed, or once
+	// for the whole pass if listing actual state itself failed (Model is
+	// empty in that case).
+	ReconcileFailed EventKind = "ReconcileFailed"
+)
+
+// Event reports one thing the reconciler did or failed to do.
+type Event struct {
+	Kind  EventKind
+	Model string
+	Err   error
+}
+
+// Controller reconciles a persisted desired-state manifest against an
+// llm.MLXModelManager's actual local cache. The zero value isn't usable;
+// build one with NewController.
+type Controller struct {
+	manager   *llm.MLXModelManager
+	modelsDir string
+
+	mu      sync.Mutex
+	desired DesiredState
+	pinned  map[string]bool
+
+	subMu sync.Mutex
+	subs  []chan Event
+
+	trigger chan struct{}
+
+	// pullFunc and deleteFunc are indirected so tests can substitute a fake
+	// instead of hitting HuggingFace; production code should never
+	// reassign them after NewController.
+	pullFunc   func(ctx context.Context, name string) error
+	deleteFunc func(name string) error
+}
+
+// NewController builds a Controller over manager, loading whatever
+// desired-state manifest is already on disk (an absent or malformed one is
+// treated as empty, matching loadUsageCache's tolerance for a missing
+// cache file).
+func NewController(manager *llm.MLXModelManager) *Controller {
+	modelsDir := manager.GetModelsDir()
+	m := loadManifest(modelsDir)
+
+	c := &Controller{
+		manager:   manager,
+		modelsDir: modelsDir,
+		desired:   m.Desired,
+		pinned:    m.Pinned,
+		trigger:   make(chan struct{}, 1),
+	}
+	c.pullFunc = func(ctx context.Context, name string) error {
+		return manager.DownloadMLXModel(ctx, name, nil)
+	}
+	c.deleteFunc = manager.DeleteModel
+	return c
+}
+
+// Run starts the reconciler goroutine: one pass immediately, then one more
+// each time TriggerReconcile is called (ollmlx serve calls it whenever the
+// MLX runner pool acquires a fresh subprocess, i.e. whenever the runner
+// "reconnects"), until ctx is done.
+func (c *Controller) Run(ctx context.Context) {
+	go func() {
+		c.Reconcile(ctx)
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-c.trigger:
+				c.Reconcile(ctx)
+			}
+		}
+	}()
+}
+
+// TriggerReconcile asks the reconciler to run again as soon as it's free,
+// coalescing with any trigger already pending so a burst of calls only
+// causes one extra pass.
+func (c *Controller) TriggerReconcile() {
+	select {
+	case c.trigger <- struct{}{}:
+	default:
+	}
+}
+
+// Desired returns the current desired-state manifest.
+func (c *Controller) Desired() DesiredState {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.desired
+}
+
+// SetDesired replaces the desired-state manifest, persists it to
+// models.json, remembers any newly-pinned names (see manifest.Pinned), and
+// triggers a reconcile pass.
+func (c *Controller) SetDesired(state DesiredState) error {
+	c.mu.Lock()
+	for _, dm := range state.Models {
+		if dm.Pinned {
+			c.pinned[dm.Name] = true
+		} else if _, ok := c.pinned[dm.Name]; ok {
+			delete(c.pinned, dm.Name)
+		}
+	}
+	c.desired = state
+	m := manifest{Desired: c.desired, Pinned: clonePinned(c.pinned)}
+	c.mu.Unlock()
+
+	if err := saveManifest(c.modelsDir, m); err != nil {
+		return fmt.Errorf("failed to save mlxcontrol state: %w", err)
+	}
+
+	c.TriggerReconcile()
+	return nil
+}
+
+// Actual returns every MLX model currently cached locally.
+func (c *Controller) Actual() ([]llm.MLXModelInfo, error) {
+	return c.manager.ListModels()
+}
+
+// Subscribe returns a channel that receives every Event from here on. The
+// channel is buffered; a subscriber that falls behind drops events rather
+// than blocking Reconcile.
+func (c *Controller) Subscribe() <-chan Event {
+	ch := make(chan Event, 16)
+	c.subMu.Lock()
+	c.subs = append(c.subs, ch)
+	c.subMu.Unlock()
+	return ch
+}
+
+func (c *Controller) emit(ev Event) {
+	c.subMu.Lock()
+	defer c.subMu.Unlock()
+	for _, ch := range c.subs {
+		select {
+		case ch <- ev:
+		default:
+		}
+	}
+}
+
+// Reconcile diffs the desired manifest against manager.ListModels and pulls
+// every missing desired model, then evicts every locally cached model
+// that's neither desired nor pinned. A pull or eviction failure is reported
+// as a ReconcileFailed event and doesn't stop the rest of the pass; Reconcile
+// itself only returns an error when listing actual state fails.
+func (c *Controller) Reconcile(ctx context.Context) error {
+	desired := c.Desired()
+
+	actual, err := c.manager.ListModels()
+	if err != nil {
+		c.emit(Event{Kind: ReconcileFailed, Err: err})
+		return fmt.Errorf("failed to list actual mlx models: %w", err)
+	}
+
+	desiredByName := desired.byName()
+
+	actualByName := make(map[string]bool, len(actual))
+	for _, info := range actual {
+		actualByName[info.Name] = true
+	}
+
+	for _, dm := range desired.Models {
+		if actualByName[dm.Name] {
+			continue
+		}
+
+		c.emit(Event{Kind: ModelLoading, Model: dm.Name})
+		if err := c.pullFunc(ctx, dm.Name); err != nil {
+			c.emit(Event{Kind: ReconcileFailed, Model: dm.Name, Err: err})
+			continue
+		}
+		c.touchLastUsed(dm.Name)
+		c.emit(Event{Kind: ModelReady, Model: dm.Name})
+	}
+
+	c.mu.Lock()
+	pinned := clonePinned(c.pinned)
+	c.mu.Unlock()
+
+	for _, info := range actual {
+		if _, ok := desiredByName[info.Name]; ok {
+			continue
+		}
+		if pinned[info.Name] {
+			continue
+		}
+
+		if err := c.deleteFunc(info.Name); err != nil {
+			c.emit(Event{Kind: ReconcileFailed, Model: info.Name, Err: err})
+			continue
+		}
+		c.emit(Event{Kind: ModelEvicted, Model: info.Name})
+	}
+
+	return nil
+}
+
+// touchLastUsed records that name was just (re)pulled, persisting the
+// updated manifest so LastUsed survives a restart.
+func (c *Controller) touchLastUsed(name string) {
+	c.mu.Lock()
+	for i := range c.desired.Models {
+		if c.desired.Models[i].Name == name {
+			c.desired.Models[i].LastUsed = time.Now()
+			break
+		}
+	}
+	m := manifest{Desired: c.desired, Pinned: clonePinned(c.pinned)}
+	c.mu.Unlock()
+
+	saveManifest(c.modelsDir, m)
+}
+
+// clonePinned copies pinned so a manifest built for saveManifest (which
+// runs outside c.mu) never shares map storage with a Controller field that
+// a later SetDesired call could mutate concurrently.
+func clonePinned(pinned map[string]bool) map[string]bool {
+	clone := make(map[string]bool, len(pinned))
+	for k, v := range pinned {
+		clone[k] = v
+	}
+	return clone
+}
@@ -0,0 +1,84 @@
+package mlxcontrol
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// DesiredModel is one entry in the control plane's desired-state manifest:
+// a model that should be pulled and kept locally cached.
+type DesiredModel struct {
+	Name         string    `json:"name"`
+	Digest       string    `json:"digest,omitempty"`
+	Quantization string    `json:"quantization,omitempty"`
+	Pinned       bool      `json:"pinned,omitempty"`
+	LastUsed     time.Time `json:"lastUsed"`
+}
+
+// DesiredState is the full manifest a caller (the /api/mlx/state PUT
+// handler, `ollmlx` CLI, or a human editing models.json directly) declares
+// as what should be locally cached.
+type DesiredState struct {
+	Models []DesiredModel `json:"models"`
+}
+
+// byName returns the desired models indexed by name, for diffing against
+// manager.ListModels().
+func (d DesiredState) byName() map[string]DesiredModel {
+	m := make(map[string]DesiredModel, len(d.Models))
+	for _, dm := range d.Models {
+		m[dm.Name] = dm
+	}
+	return m
+}
+
+// manifest is what's actually persisted to models.json: the desired state
+// plus pinned, which accumulates every name ever marked Pinned across
+// SetDesired calls, so a model stays protected from eviction even after a
+// later desired state drops it, until an explicit Pinned:false entry for
+// the same name clears it.
+type manifest struct {
+	Desired DesiredState    `json:"desired"`
+	Pinned  map[string]bool `json:"pinned,omitempty"`
+}
+
+// statePath returns where the control plane's desired-state manifest lives
+// within modelsDir, alongside usage.cache and the blob store.
+func statePath(modelsDir string) string {
+	return filepath.Join(modelsDir, "models.json")
+}
+
+// loadManifest reads models.json from modelsDir, returning an empty
+// manifest if it doesn't exist yet or is malformed.
+func loadManifest(modelsDir string) manifest {
+	data, err := os.ReadFile(statePath(modelsDir))
+	if err != nil {
+		return manifest{Pinned: make(map[string]bool)}
+	}
+
+	var m manifest
+	if err := json.Unmarshal(data, &m); err != nil {
+		return manifest{Pinned: make(map[string]bool)}
+	}
+	if m.Pinned == nil {
+		m.Pinned = make(map[string]bool)
+	}
+	return m
+}
+
+// saveManifest writes m to models.json atomically (tmp file + rename), the
+// same pattern mlx_crawler.go uses for usage.cache.
+func saveManifest(modelsDir string, m manifest) error {
+	data, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	tmp := statePath(modelsDir) + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, statePath(modelsDir))
+}
@@ -0,0 +1,200 @@
+package mlxrunner
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// grammar.go compiles a JSON Schema (or a bare "format: json" request) into
+// a GBNF-style grammar carried over gRPC in PredictRequest.Grammar. The
+// byte-level NFA, the tokenizer-vocab trie, and the per-decoding-step logit
+// masking this enables all run inside the Python backend, where the
+// tokenizer lives: compiling the grammar from "token -> legal next byte
+// set" down to "mask these vocab entries" is the backend's job once it has
+// this text. This file only owns turning a schema into that text, so a
+// caller gets constrained decoding without hand-rolling a grammar string.
+//
+// Grammars are the structured-output replacement for salvaging tool calls
+// out of free-form text with regex: instead of hoping the model's output
+// happens to contain valid JSON, the sampler is prevented from emitting
+// anything else in the first place. Callers should still fall back to
+// parsing free-form output when Server.SupportsGrammar reports false.
+
+// Grammar is a compiled GBNF-style grammar ready to send to the backend.
+type Grammar struct {
+	Source string
+}
+
+// jsonValueRules are the generic JSON value rules every compiled schema
+// falls back on for sub-schemas it doesn't specialize (e.g. an
+// unconstrained "additionalProperties" value), mirroring llama.cpp's
+// json.gbnf.
+const jsonValueRules = `value ::= object | array | string | number | boolean | "null"
+object ::= "{" ws (member ("," ws member)*)? ws "}"
+member ::= string ws ":" ws value
+array ::= "[" ws (value ("," ws value)*)? ws "]"
+string ::= "\"" char* "\""
+char ::= [^"\\] | "\\" (["\\/bfnrt] | "u" hex hex hex hex)
+hex ::= [0-9a-fA-F]
+number ::= "-"? int frac? exp?
+int ::= "0" | [1-9] [0-9]*
+frac ::= "." [0-9]+
+exp ::= ("e" | "E") ("+" | "-")? [0-9]+
+boolean ::= "true" | "false"
+ws ::= [ \t\n]*
+`
+
+// CompileFormat compiles an api.GenerateRequest/ChatRequest Format value
+// into a Grammar. format may be the literal JSON string `"json"` or a JSON
+// Schema document; an empty format means "no grammar", and CompileFormat
+// returns (nil, nil) rather than an error.
+func CompileFormat(format json.RawMessage) (*Grammar, error) {
+	trimmed := strings.TrimSpace(string(format))
+	if trimmed == "" {
+		return nil, nil
+	}
+	if trimmed == `"json"` {
+		return &Grammar{Source: "root ::= value\n" + jsonValueRules}, nil
+	}
+
+	var schema map[string]any
+	if err := json.Unmarshal(format, &schema); err != nil {
+		return nil, fmt.Errorf("format is neither \"json\" nor a JSON Schema object: %w", err)
+	}
+	return CompileJSONSchema(schema)
+}
+
+// CompileJSONSchema walks a JSON Schema document and produces a grammar
+// whose root rule accepts exactly the values the schema allows. Schema
+// shapes it doesn't specialize (complex patterns, $ref, oneOf/anyOf, …)
+// fall back to the generic "value" rule rather than failing outright: an
+// overly permissive grammar still rules out most malformed output.
+func CompileJSONSchema(schema map[string]any) (*Grammar, error) {
+	var b strings.Builder
+	b.WriteString("root ::= ")
+	writeSchemaRule(&b, schema)
+	b.WriteString("\n")
+	b.WriteString(jsonValueRules)
+	return &Grammar{Source: b.String()}, nil
+}
+
+// ToolSchema is the subset of an api.Tool the grammar compiler needs: a
+// name to match literally and a JSON Schema for its arguments.
+type ToolSchema struct {
+	Name       string
+	Parameters map[string]any
+}
+
+// CompileToolCallGrammar builds a grammar that accepts a single tool call
+// shaped {"name": "<tool name>", "arguments": <that tool's parameter
+// schema>}, with one alternative per tool. A chat handler that sets
+// api.ChatRequest.Tools should compile this up front and pass it to
+// Predict, instead of relying on parseToolCallsFromText to salvage a call
+// out of whatever the model happened to say.
+func CompileToolCallGrammar(tools []ToolSchema) (*Grammar, error) {
+	if len(tools) == 0 {
+		return nil, fmt.Errorf("no tools to compile a grammar for")
+	}
+
+	var b strings.Builder
+	b.WriteString("root ::= ")
+	for i, tool := range tools {
+		if i > 0 {
+			b.WriteString(" | ")
+		}
+		nameLit, _ := json.Marshal("name")
+		toolNameLit, _ := json.Marshal(tool.Name)
+		argsLit, _ := json.Marshal("arguments")
+
+		b.WriteString("(\"{\" ws ")
+		b.WriteString(string(nameLit))
+		b.WriteString(" ws \":\" ws ")
+		b.WriteString(string(toolNameLit))
+		b.WriteString(" ws \",\" ws ")
+		b.WriteString(string(argsLit))
+		b.WriteString(" ws \":\" ws ")
+		writeSchemaRule(&b, tool.Parameters)
+		b.WriteString(" ws \"}\")")
+	}
+	b.WriteString("\n")
+	b.WriteString(jsonValueRules)
+	return &Grammar{Source: b.String()}, nil
+}
+
+func writeSchemaRule(b *strings.Builder, schema map[string]any) {
+	t, _ := schema["type"].(string)
+	switch t {
+	case "object":
+		writeObjectRule(b, schema)
+	case "string":
+		writeStringRule(b, schema)
+	case "number", "integer":
+		b.WriteString("number")
+	case "boolean":
+		b.WriteString("boolean")
+	case "array":
+		writeArrayRule(b, schema)
+	default:
+		b.WriteString("value")
+	}
+}
+
+func writeStringRule(b *strings.Builder, schema map[string]any) {
+	enumVals, ok := schema["enum"].([]any)
+	if !ok || len(enumVals) == 0 {
+		b.WriteString("string")
+		return
+	}
+
+	b.WriteString("(")
+	for i, v := range enumVals {
+		if i > 0 {
+			b.WriteString(" | ")
+		}
+		lit, _ := json.Marshal(fmt.Sprintf("%v", v))
+		b.WriteString(string(lit))
+	}
+	b.WriteString(")")
+}
+
+func writeArrayRule(b *strings.Builder, schema map[string]any) {
+	items, _ := schema["items"].(map[string]any)
+
+	var item strings.Builder
+	if items != nil {
+		writeSchemaRule(&item, items)
+	} else {
+		item.WriteString("value")
+	}
+
+	fmt.Fprintf(b, "(\"[\" ws (%s (\",\" ws %s)*)? ws \"]\")", item.String(), item.String())
+}
+
+func writeObjectRule(b *strings.Builder, schema map[string]any) {
+	props, _ := schema["properties"].(map[string]any)
+	if len(props) == 0 {
+		b.WriteString("object")
+		return
+	}
+
+	names := make([]string, 0, len(props))
+	for name := range props {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	b.WriteString("(\"{\" ws ")
+	for i, name := range names {
+		if i > 0 {
+			b.WriteString(" \",\" ws ")
+		}
+		propSchema, _ := props[name].(map[string]any)
+		lit, _ := json.Marshal(name)
+		b.WriteString(string(lit))
+		b.WriteString(" ws \":\" ws ")
+		writeSchemaRule(b, propSchema)
+	}
+	b.WriteString(" ws \"}\")")
+}
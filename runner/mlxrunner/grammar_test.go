@@ -0,0 +1,179 @@
+package mlxrunner
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+// TestCompileFormatEmpty verifies an empty format means "no grammar"
+// rather than an error, matching Predict's "nil grammar = unconstrained"
+// contract.
+func TestCompileFormatEmpty(t *testing.T) {
+	g, err := CompileFormat(json.RawMessage(""))
+	if err != nil {
+		t.Fatalf("CompileFormat(\"\") returned an error: %v", err)
+	}
+	if g != nil {
+		t.Fatalf("CompileFormat(\"\") = %+v, want nil", g)
+	}
+
+	g, err = CompileFormat(json.RawMessage("   "))
+	if err != nil {
+		t.Fatalf("CompileFormat(whitespace) returned an error: %v", err)
+	}
+	if g != nil {
+		t.Fatalf("CompileFormat(whitespace) = %+v, want nil", g)
+	}
+}
+
+// TestCompileFormatJSONLiteral verifies the bare `"json"` format produces a
+// grammar rooted at the generic JSON value rule, not a schema-specialized
+// one.
+func TestCompileFormatJSONLiteral(t *testing.T) {
+	g, err := CompileFormat(json.RawMessage(`"json"`))
+	if err != nil {
+		t.Fatalf("CompileFormat(\"json\") failed: %v", err)
+	}
+	if g == nil {
+		t.Fatal("expected a non-nil grammar for format \"json\"")
+	}
+	if !strings.HasPrefix(g.Source, "root ::= value\n") {
+		t.Errorf("grammar source = %q, want it to start with the generic value root", g.Source)
+	}
+}
+
+// TestCompileFormatInvalid verifies a format that's neither "json" nor a
+// JSON Schema object is reported as an error instead of silently producing
+// a useless grammar.
+func TestCompileFormatInvalid(t *testing.T) {
+	if _, err := CompileFormat(json.RawMessage(`not json at all`)); err == nil {
+		t.Fatal("expected an error for a format that isn't JSON")
+	}
+}
+
+// TestCompileJSONSchemaObject verifies an object schema's root rule is
+// ordered by sorted property name (so grammar output is deterministic
+// regardless of Go's randomized map iteration) and specializes each
+// property's type.
+func TestCompileJSONSchemaObject(t *testing.T) {
+	schema := map[string]any{
+		"type": "object",
+		"properties": map[string]any{
+			"name": map[string]any{"type": "string"},
+			"age":  map[string]any{"type": "integer"},
+		},
+	}
+
+	g, err := CompileJSONSchema(schema)
+	if err != nil {
+		t.Fatalf("CompileJSONSchema failed: %v", err)
+	}
+
+	ageIdx := strings.Index(g.Source, `"age"`)
+	nameIdx := strings.Index(g.Source, `"name"`)
+	if ageIdx == -1 || nameIdx == -1 {
+		t.Fatalf("expected both property names as literals in grammar source, got %q", g.Source)
+	}
+	if ageIdx > nameIdx {
+		t.Errorf("expected sorted property order (age before name), got %q", g.Source)
+	}
+	if !strings.Contains(g.Source, "number") {
+		t.Errorf("expected the integer property to compile to the number rule, got %q", g.Source)
+	}
+}
+
+// TestCompileJSONSchemaStringEnum verifies a string schema with an enum
+// compiles to an alternation of its literal values instead of the
+// unconstrained string rule.
+func TestCompileJSONSchemaStringEnum(t *testing.T) {
+	schema := map[string]any{
+		"type": "object",
+		"properties": map[string]any{
+			"status": map[string]any{
+				"type": "string",
+				"enum": []any{"pending", "done"},
+			},
+		},
+	}
+
+	g, err := CompileJSONSchema(schema)
+	if err != nil {
+		t.Fatalf("CompileJSONSchema failed: %v", err)
+	}
+	if !strings.Contains(g.Source, `"pending"`) || !strings.Contains(g.Source, `"done"`) {
+		t.Errorf("expected both enum values as literals in grammar source, got %q", g.Source)
+	}
+}
+
+// TestCompileJSONSchemaArray verifies an array schema's root rule
+// specializes its items instead of falling back to the generic value rule.
+func TestCompileJSONSchemaArray(t *testing.T) {
+	schema := map[string]any{
+		"type":  "array",
+		"items": map[string]any{"type": "string"},
+	}
+
+	g, err := CompileJSONSchema(schema)
+	if err != nil {
+		t.Fatalf("CompileJSONSchema failed: %v", err)
+	}
+	if !strings.HasPrefix(g.Source, `root ::= ("[" ws (string`) {
+		t.Errorf("grammar source = %q, want an array rule over the string item rule", g.Source)
+	}
+}
+
+// TestCompileJSONSchemaUnspecialized verifies a schema shape the compiler
+// doesn't specialize (no recognized "type") falls back to the generic
+// value rule rather than failing.
+func TestCompileJSONSchemaUnspecialized(t *testing.T) {
+	g, err := CompileJSONSchema(map[string]any{"oneOf": []any{}})
+	if err != nil {
+		t.Fatalf("CompileJSONSchema failed: %v", err)
+	}
+	if !strings.HasPrefix(g.Source, "root ::= value\n") {
+		t.Errorf("grammar source = %q, want it to fall back to the generic value root", g.Source)
+	}
+}
+
+// TestCompileToolCallGrammarNoTools verifies compiling against an empty
+// tool list is an error rather than producing a grammar that accepts
+// nothing.
+func TestCompileToolCallGrammarNoTools(t *testing.T) {
+	if _, err := CompileToolCallGrammar(nil); err == nil {
+		t.Fatal("expected an error when compiling a tool-call grammar with no tools")
+	}
+}
+
+// TestCompileToolCallGrammarMultipleTools verifies the compiled grammar
+// offers one alternative per tool, each gated on that tool's own literal
+// name and specializing its own parameter schema.
+func TestCompileToolCallGrammarMultipleTools(t *testing.T) {
+	tools := []ToolSchema{
+		{
+			Name: "get_weather",
+			Parameters: map[string]any{
+				"type":       "object",
+				"properties": map[string]any{"location": map[string]any{"type": "string"}},
+			},
+		},
+		{
+			Name:       "get_time",
+			Parameters: map[string]any{"type": "object"},
+		},
+	}
+
+	g, err := CompileToolCallGrammar(tools)
+	if err != nil {
+		t.Fatalf("CompileToolCallGrammar failed: %v", err)
+	}
+
+	for _, want := range []string{`"get_weather"`, `"get_time"`, `"location"`} {
+		if !strings.Contains(g.Source, want) {
+			t.Errorf("expected grammar source to contain %q, got %q", want, g.Source)
+		}
+	}
+	if !strings.Contains(g.Source, " | ") {
+		t.Errorf("expected root rule to alternate between the two tools, got %q", g.Source)
+	}
+}
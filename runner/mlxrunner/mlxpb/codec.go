@@ -0,0 +1,49 @@
+package mlxpb
+
+import (
+	"bytes"
+	"encoding/gob"
+	"fmt"
+
+	"google.golang.org/grpc/encoding"
+)
+
+// codecName is the content-subtype grpc-go uses to select Codec below. It
+// doesn't need to match anything protobuf-related: it just has to be
+// distinct from the "proto" codec grpc-go registers by default, which
+// requires every message to implement proto.Message - something the plain
+// structs in mlx.pb.go deliberately don't (see Codec's doc comment).
+const codecName = "mlxgob"
+
+func init() {
+	encoding.RegisterCodec(Codec{})
+}
+
+// Codec is a grpc-go encoding.Codec for the MLXBackend service's message
+// types. mlx.pb.go's types are hand-written plain structs rather than
+// protoc-gen-go output wired to the real protobuf runtime (no Reset/
+// ProtoReflect), so they don't satisfy proto.Message and can't go through
+// grpc-go's default "proto" codec. Gob round-trips them fine - every field
+// is a concrete exported type, none are interfaces - so it's used here
+// instead of pulling in a second serialization format. Every call site that
+// dials or serves MLXBackendClient/Server must force this codec (see
+// dialUnix in server.go and stubbackend/main.go) since the server and
+// client must agree on content-subtype for Invoke/NewStream to pick it up.
+type Codec struct{}
+
+func (Codec) Name() string { return codecName }
+
+func (Codec) Marshal(v any) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(v); err != nil {
+		return nil, fmt.Errorf("mlxpb: gob marshal failed: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+func (Codec) Unmarshal(data []byte, v any) error {
+	if err := gob.NewDecoder(bytes.NewReader(data)).Decode(v); err != nil {
+		return fmt.Errorf("mlxpb: gob unmarshal failed: %w", err)
+	}
+	return nil
+}
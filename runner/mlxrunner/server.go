@@ -0,0 +1,362 @@
+// Package mlxrunner spawns the Python MLX backend as a subprocess and
+// speaks the MLXBackend gRPC contract (proto/mlx/v1/mlx.proto) to it over a
+// Unix domain socket. It replaces the runner's old ad-hoc HTTP /health,
+// /load, and /completion endpoints: the same split (a thin Go supervisor in
+// front of a subprocess speaking a stable RPC contract) that LocalAI uses
+// for its backends, so other runtimes can eventually be plugged in behind
+// the same Server shape.
+package mlxrunner
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+
+	"github.com/ollama/ollama/runner/mlxrunner/mlxpb"
+)
+
+// Token is one piece of a streamed completion, handed to the caller's
+// channel by Server.Predict. PromptTokens, CompletionTokens,
+// PromptEvalDuration, and EvalDuration are running totals: they hold
+// whatever the backend has counted so far, so a caller that stops
+// consuming early (context cancellation, client disconnect) still sees
+// accurate partial usage on the last Token it received.
+type Token struct {
+	Text               string
+	Done               bool
+	DoneReason         string
+	Err                error
+	PromptTokens       int
+	CompletionTokens   int
+	PromptEvalDuration time.Duration
+	EvalDuration       time.Duration
+}
+
+// Server supervises a single Python MLX backend subprocess and provides the
+// Go-side entry points (Load, Predict, Embed, Tokenize, Health) the rest of
+// ollmlx calls. Only one model is ever loaded at a time; callers that want
+// to serve several models concurrently run several Servers.
+type Server struct {
+	modelPath string
+	sockPath  string
+
+	// command and args, when set, override pythonBackendCmd: this is how
+	// NewServerWithCommand plugs in a third-party runtime that speaks the
+	// same gRPC contract instead of ollmlx's own Python MLX backend.
+	command string
+	args    []string
+
+	cmd  *exec.Cmd
+	conn *grpc.ClientConn
+	rpc  mlxpb.MLXBackendClient
+
+	// done is closed once the backend subprocess has exited, whether from
+	// Close/terminate or a crash; exitErr holds whatever cmd.Wait returned.
+	// Exited lets callers like MLXRunnerPool notice a crashed subprocess
+	// without having to fail an RPC against it first.
+	done    chan struct{}
+	exitErr error
+
+	mu         sync.Mutex
+	cond       *sync.Cond
+	ready      sync.WaitGroup
+	loadedOnce bool
+
+	startedAt time.Time
+}
+
+// NewServer constructs a Server for modelPath that launches ollmlx's own
+// Python MLX backend. Start must be called before any RPC will succeed.
+func NewServer(modelPath string) *Server {
+	s := &Server{modelPath: modelPath}
+	s.cond = sync.NewCond(&s.mu)
+	s.ready.Add(1)
+	return s
+}
+
+// NewServerWithCommand constructs a Server that supervises command/args
+// instead of the built-in Python MLX backend, for any other runtime that
+// implements the same gRPC contract (proto/mlx/v1/mlx.proto) — the
+// mechanism llm.ExternalBackend uses to auto-load third-party runtimes like
+// llama.cpp-metal or vLLM. "--socket <path>" is appended to args the same
+// way it's appended for the built-in backend; command is responsible for
+// creating that socket and serving MLXBackend on it.
+func NewServerWithCommand(modelPath, command string, args []string) *Server {
+	s := &Server{modelPath: modelPath, command: command, args: args}
+	s.cond = sync.NewCond(&s.mu)
+	s.ready.Add(1)
+	return s
+}
+
+// pythonBackendCmd returns the command used to launch the Python MLX
+// backend, overridable via OLLMLX_MLX_BACKEND_CMD for development and
+// testing (e.g. pointing at a stub backend binary).
+func pythonBackendCmd(sockPath string) (string, []string) {
+	if override := os.Getenv("OLLMLX_MLX_BACKEND_CMD"); override != "" {
+		return override, []string{"--socket", sockPath}
+	}
+	return "python3", []string{"-m", "ollmlx_backend.grpc_server", "--socket", sockPath}
+}
+
+// Start spawns the Python MLX backend subprocess (or the command given to
+// NewServerWithCommand) and dials its gRPC socket, blocking until the
+// connection is up. It does not call Health; callers poll that themselves.
+// The subprocess is expected to create sockPath itself and start serving
+// once it's ready to accept connections.
+func (s *Server) Start(ctx context.Context) error {
+	runtimeDir, err := os.MkdirTemp("", "ollmlx-mlxrunner-*")
+	if err != nil {
+		return fmt.Errorf("failed to create runner runtime dir: %w", err)
+	}
+	s.sockPath = filepath.Join(runtimeDir, "backend.sock")
+
+	name, args := s.command, s.args
+	if name == "" {
+		name, args = pythonBackendCmd(s.sockPath)
+	} else {
+		args = append(append([]string{}, args...), "--socket", s.sockPath)
+	}
+	cmd := exec.CommandContext(ctx, name, args...)
+	cmd.Env = append(os.Environ(), fmt.Sprintf("OLLMLX_MODEL_PATH=%s", s.modelPath))
+	cmd.Stdout = os.Stderr
+	cmd.Stderr = os.Stderr
+
+	if err := cmd.Start(); err != nil {
+		os.RemoveAll(runtimeDir)
+		return fmt.Errorf("failed to start mlx backend: %w", err)
+	}
+	s.cmd = cmd
+	s.done = make(chan struct{})
+	go func() {
+		err := cmd.Wait()
+		s.mu.Lock()
+		s.exitErr = err
+		s.mu.Unlock()
+		close(s.done)
+	}()
+
+	conn, err := dialUnix(ctx, s.sockPath)
+	if err != nil {
+		s.terminate()
+		return fmt.Errorf("failed to dial mlx backend: %w", err)
+	}
+	s.conn = conn
+	s.rpc = mlxpb.NewMLXBackendClient(conn)
+	s.startedAt = time.Now()
+
+	return nil
+}
+
+// dialUnix connects to a gRPC server listening on a Unix domain socket,
+// retrying briefly while the subprocess finishes creating it.
+func dialUnix(ctx context.Context, sockPath string) (*grpc.ClientConn, error) {
+	dialCtx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	return grpc.DialContext(dialCtx, "unix:"+sockPath,
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+		grpc.WithBlock(),
+		grpc.WithContextDialer(func(ctx context.Context, addr string) (net.Conn, error) {
+			var d net.Dialer
+			return d.DialContext(ctx, "unix", sockPath)
+		}),
+		// mlxpb's message types aren't real protoc-gen-go output (see
+		// mlxpb.Codec's doc comment), so every call on this connection must
+		// go through mlxpb's own codec instead of grpc-go's default "proto"
+		// one, which requires proto.Message.
+		grpc.WithDefaultCallOptions(grpc.ForceCodec(mlxpb.Codec{})),
+	)
+}
+
+// Load asks the backend to load modelPath, replacing s.modelPath, and
+// signals s.ready (first success only) and s.cond. The other RPCs do not
+// wait on either; they go straight to the backend.
+func (s *Server) Load(ctx context.Context, modelPath string) error {
+	resp, err := s.rpc.Load(ctx, &mlxpb.LoadRequest{ModelPath: modelPath})
+	if err != nil {
+		return fmt.Errorf("load rpc failed: %w", err)
+	}
+	if !resp.Ok {
+		return fmt.Errorf("backend failed to load %s: %s", modelPath, resp.Error)
+	}
+
+	s.mu.Lock()
+	s.modelPath = modelPath
+	firstLoad := !s.loadedOnce
+	s.loadedOnce = true
+	s.mu.Unlock()
+
+	if firstLoad {
+		s.ready.Done()
+	}
+	s.cond.Broadcast()
+	return nil
+}
+
+// Predict streams a completion, delivering tokens on the returned channel.
+// The channel is closed once the final chunk (Done == true) has been sent
+// or the stream errors. grammar, if non-empty, is a GBNF-style grammar
+// (see CompileFormat) the backend uses to mask illegal tokens at each
+// decoding step; callers should first confirm support via Status, since a
+// backend that doesn't understand grammars may otherwise ignore it silently.
+// images, if non-empty, holds already-resized-and-encoded pixel data for a
+// VLM prompt (see server.prepareVisionInput); backends serving a text-only
+// model are expected to ignore it.
+func (s *Server) Predict(ctx context.Context, prompt string, maxTokens int, temperature, topP float32, stop []string, grammar string, images [][]byte) (<-chan Token, error) {
+	stream, err := s.rpc.PredictStream(ctx, &mlxpb.PredictRequest{
+		Prompt:      prompt,
+		MaxTokens:   int32(maxTokens),
+		Temperature: temperature,
+		TopP:        topP,
+		Stop:        stop,
+		Grammar:     grammar,
+		Images:      images,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("predict rpc failed: %w", err)
+	}
+
+	out := make(chan Token, 16)
+	go func() {
+		defer close(out)
+		for {
+			chunk, err := stream.Recv()
+			if err == io.EOF {
+				return
+			}
+			if err != nil {
+				out <- Token{Done: true, Err: err}
+				return
+			}
+
+			out <- Token{
+				Text:               chunk.Token,
+				Done:               chunk.Done,
+				DoneReason:         chunk.DoneReason,
+				PromptTokens:       int(chunk.PromptTokens),
+				CompletionTokens:   int(chunk.CompletionTokens),
+				PromptEvalDuration: time.Duration(chunk.PromptEvalDurationNs),
+				EvalDuration:       time.Duration(chunk.EvalDurationNs),
+			}
+			if chunk.Error != "" {
+				out <- Token{Done: true, Err: fmt.Errorf("%s", chunk.Error)}
+				return
+			}
+			if chunk.Done {
+				return
+			}
+		}
+	}()
+
+	return out, nil
+}
+
+// Embed returns one embedding vector per input string, in order.
+func (s *Server) Embed(ctx context.Context, input []string) ([][]float32, error) {
+	resp, err := s.rpc.Embed(ctx, &mlxpb.EmbedRequest{Input: input})
+	if err != nil {
+		return nil, fmt.Errorf("embed rpc failed: %w", err)
+	}
+
+	vectors := make([][]float32, len(resp.Embeddings))
+	for i, v := range resp.Embeddings {
+		vectors[i] = v.Values
+	}
+	return vectors, nil
+}
+
+// Tokenize returns the token IDs the loaded model's tokenizer produces for
+// text.
+func (s *Server) Tokenize(ctx context.Context, text string) ([]int32, error) {
+	resp, err := s.rpc.Tokenize(ctx, &mlxpb.TokenizeRequest{Text: text})
+	if err != nil {
+		return nil, fmt.Errorf("tokenize rpc failed: %w", err)
+	}
+	return resp.Tokens, nil
+}
+
+// Health reports whether the backend process is alive and able to serve
+// requests, independent of whether a model is loaded.
+func (s *Server) Health(ctx context.Context) error {
+	resp, err := s.rpc.Health(ctx, &mlxpb.HealthRequest{})
+	if err != nil {
+		return fmt.Errorf("health rpc failed: %w", err)
+	}
+	if !resp.Ok {
+		return fmt.Errorf("backend unhealthy: %s", resp.Error)
+	}
+	return nil
+}
+
+// Status reports what the backend is currently doing.
+func (s *Server) Status(ctx context.Context) (*mlxpb.StatusResponse, error) {
+	return s.rpc.Status(ctx, &mlxpb.StatusRequest{})
+}
+
+// SupportsGrammar reports whether the backend can apply a
+// PredictRequest.Grammar, so callers can fall back to parsing free-form
+// output instead of sending a grammar the backend would silently ignore.
+// Any error talking to the backend is treated as "no support".
+func (s *Server) SupportsGrammar(ctx context.Context) bool {
+	status, err := s.Status(ctx)
+	if err != nil {
+		return false
+	}
+	return status.SupportsGrammar
+}
+
+// Close terminates the backend subprocess and releases the gRPC connection.
+// Like terminate, it doesn't take s.mu: s.conn is set once during Start
+// before the Server is shared, and terminate blocks on the reaper goroutine
+// from Start, which itself needs s.mu to record exitErr.
+func (s *Server) Close() error {
+	var err error
+	if s.conn != nil {
+		err = s.conn.Close()
+	}
+	s.terminate()
+	return err
+}
+
+// terminate kills the backend subprocess and removes its runtime directory.
+// Safe to call whether or not s.mu is held: it only touches fields set once
+// during Start, before the Server is shared with any other goroutine. The
+// Start goroutine that reaps the process owns calling cmd.Wait (a *Cmd may
+// only be waited on once), so terminate just kills it and waits for that
+// goroutine to close s.done.
+func (s *Server) terminate() {
+	if s.cmd != nil && s.cmd.Process != nil {
+		s.cmd.Process.Kill()
+		if s.done != nil {
+			<-s.done
+		}
+	}
+	if s.sockPath != "" {
+		os.RemoveAll(filepath.Dir(s.sockPath))
+	}
+}
+
+// Exited returns a channel that's closed once the backend subprocess has
+// exited, whether from Close or a crash. Callers that keep a Server warm
+// across requests (MLXRunnerPool) use this to notice a dead subprocess
+// without first having to fail an RPC against it.
+func (s *Server) Exited() <-chan struct{} {
+	return s.done
+}
+
+// ExitErr returns whatever error cmd.Wait reported once Exited is closed;
+// it's nil before then.
+func (s *Server) ExitErr() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.exitErr
+}
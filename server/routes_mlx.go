@@ -2,50 +2,78 @@ package server
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
+	"io"
 	"log/slog"
 	"net/http"
-	"os"
-	"os/exec"
+	"regexp"
+	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/ollama/ollama/api"
 	"github.com/ollama/ollama/llm"
+	"github.com/ollama/ollama/llm/mlxcontrol"
+	"github.com/ollama/ollama/runner/mlxrunner"
 )
 
-// PullMLXModel downloads an MLX model from HuggingFace
+// PullMLXModel downloads an MLX model from HuggingFace. modelName may be a
+// full "org/repo" reference, or a short curated name from the gallery
+// catalog (see llm.LoadMLXCatalog) such as "gemma3:270m-4bit".
 func PullMLXModel(ctx context.Context, modelName string, fn func(api.ProgressResponse)) error {
-	slog.Info("pulling MLX model from HuggingFace", "model", modelName)
-
 	manager := llm.NewMLXModelManager()
 
-	// Check if model already exists
-	if manager.ModelExists(modelName) {
+	repo := modelName
+	var catalogEntry llm.MLXCatalogEntry
+	var haveCatalogEntry bool
+	if llm.IsShortCatalogName(modelName) {
+		catalog, err := llm.LoadMLXCatalog(ctx)
+		if err != nil {
+			slog.Warn("failed to load full mlx gallery catalog, continuing with what loaded", "error", err)
+		}
+		entry, ok := catalog.Find(modelName)
+		if !ok {
+			return fmt.Errorf("%q is not a known gallery name; pass a full org/repo reference instead", modelName)
+		}
+		repo = entry.Repo
+		catalogEntry = entry
+		haveCatalogEntry = true
+	}
+
+	slog.Info("pulling MLX model from HuggingFace", "model", repo)
+
+	if manager.ModelExists(repo) {
 		fn(api.ProgressResponse{
-			Status: fmt.Sprintf("model %s already exists", modelName),
+			Status: fmt.Sprintf("model %s already exists", repo),
 		})
 		return nil
 	}
 
-	// Download the model
 	fn(api.ProgressResponse{
-		Status: fmt.Sprintf("pulling MLX model %s from HuggingFace", modelName),
+		Status: fmt.Sprintf("pulling MLX model %s from HuggingFace", repo),
 	})
 
-	err := manager.DownloadMLXModel(modelName, func(status string, progress float64) {
+	err := manager.DownloadMLXModel(ctx, repo, func(p llm.MLXDownloadProgress) {
 		fn(api.ProgressResponse{
-			Status:    status,
-			Completed: int64(progress),
-			Total:     100,
+			Status:    p.Status,
+			Completed: p.Completed,
+			Total:     p.Total,
 		})
 	})
-
 	if err != nil {
 		return fmt.Errorf("failed to download MLX model: %w", err)
 	}
 
+	if haveCatalogEntry {
+		localName := strings.ReplaceAll(repo, "/", "_")
+		if err := llm.PersistCatalogEntry(manager.GetModelPath(localName), catalogEntry); err != nil {
+			slog.Warn("failed to persist gallery entry alongside model", "model", repo, "error", err)
+		}
+	}
+
 	fn(api.ProgressResponse{
 		Status: "success",
 	})
@@ -94,11 +122,18 @@ func ShowMLXModel(modelName string) (*api.ShowResponse, error) {
 		return nil, err
 	}
 
+	paramCount, err := manager.ParameterCount(localName)
+	if err != nil {
+		// No safetensors shards to compute an exact count from (yet); fall
+		// back to whatever parameter-size string GetModelInfo came up with.
+		paramCount = parseParameterCount(info.ParameterSize)
+	}
+
 	return &api.ShowResponse{
 		ModelInfo: map[string]any{
-			"general.architecture": "mlx",
-			"general.family":       info.Family,
-			"general.parameter_count": float64(parseParameterCount(info.ParameterSize)),
+			"general.architecture":       "mlx",
+			"general.family":             info.Family,
+			"general.parameter_count":    float64(paramCount),
 			"general.quantization_level": info.QuantizLevel,
 		},
 		ModifiedAt: info.ModifiedAt,
@@ -117,29 +152,310 @@ func DeleteMLXModel(modelName string) error {
 	return manager.DeleteModel(modelName)
 }
 
+// MLXGalleryHandler serves /api/gallery: the merged catalog of curated MLX
+// models (see llm.LoadMLXCatalog), optionally filtered by a "q" query
+// parameter the same way `ollmlx gallery search` is.
+func (s *Server) MLXGalleryHandler(c *gin.Context) {
+	catalog, err := llm.LoadMLXCatalog(c.Request.Context())
+	if err != nil {
+		slog.Warn("failed to load full mlx gallery catalog, returning what loaded", "error", err)
+	}
+
+	c.JSON(http.StatusOK, gin.H{"models": catalog.Search(c.Query("q"))})
+}
+
+// mlxStateResponse is the body MLXStateHandler returns for both GET and PUT:
+// the desired-state manifest alongside what's actually cached locally, so a
+// caller can see convergence progress without a second round-trip.
+type mlxStateResponse struct {
+	Desired mlxcontrol.DesiredState `json:"desired"`
+	Actual  []llm.MLXModelInfo      `json:"actual"`
+}
+
+// MLXStateHandler serves /api/mlx/state: GET returns the desired-state
+// manifest (see llm/mlxcontrol) alongside what's actually cached locally;
+// PUT replaces the desired manifest and runs a reconcile pass before
+// responding, so the actual field in its response already reflects
+// whatever pulls/evictions that manifest required.
+func (s *Server) MLXStateHandler(c *gin.Context) {
+	ctl := defaultMLXController()
+
+	if c.Request.Method == http.MethodPut {
+		var desired mlxcontrol.DesiredState
+		if err := c.ShouldBindJSON(&desired); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		if err := ctl.SetDesired(desired); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		if err := ctl.Reconcile(c.Request.Context()); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	actual, err := ctl.Actual()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, mlxStateResponse{Desired: ctl.Desired(), Actual: actual})
+}
+
 // IsMLXModelReference checks if a model name is an MLX model reference
 func IsMLXModelReference(modelName string) bool {
-	// MLX models typically come from HuggingFace with format:
-	// - "mlx-community/ModelName"
-	// - contain "mlx" in the name
-	// - or are stored in the MLX models directory
+	_, ok := defaultBackendRegistry().Detect(modelName)
+	return ok
+}
 
-	if strings.HasPrefix(modelName, "mlx-community/") {
-		return true
+// mlxRunnerHandle is the subset of *mlxrunner.Server that generateMLXModel
+// depends on, so tests can substitute a fake runner without going through a
+// real gRPC-speaking Python subprocess.
+type mlxRunnerHandle interface {
+	Load(ctx context.Context, modelPath string) error
+	Predict(ctx context.Context, prompt string, maxTokens int, temperature, topP float32, stop []string, grammar string, images [][]byte) (<-chan mlxrunner.Token, error)
+	Health(ctx context.Context) error
+	SupportsGrammar(ctx context.Context) bool
+	Close() error
+}
+
+// mlxRunnerPool keeps a warm runner subprocess per model path alive across
+// requests, instead of generateMLXModel spawning and killing one per call.
+// mlxRunnerPoolMaxLoaded bounds how many distinct models can have a warm
+// runner at once before the least-recently-used idle one is evicted.
+const mlxRunnerPoolMaxLoaded = 3
+
+var mlxRunnerPool = llm.NewMLXRunnerPool(mlxRunnerPoolMaxLoaded)
+
+// mlxBackendRegistry routes a model reference to whichever llm.Backend
+// claims it: the built-in MLX backend, plus any third-party runtime
+// configured under ~/.ollmlx/backends (see llm.LoadExternalBackendConfigs).
+// It's populated once, lazily, by defaultBackendRegistry so callers never
+// race its construction.
+//
+// mlxController is the one long-lived mlxcontrol.Controller the process
+// keeps running for the lifetime of `ollmlx serve`, built over the same
+// manager as the MLX backend above. defaultBackendRegistry starts it and
+// wires mlxRunnerPool.OnFreshRunner to its TriggerReconcile, so MLXStateHandler
+// and every other caller share the reconciler a background runner reconnect
+// actually drives instead of each request running its own isolated pass.
+var (
+	mlxBackendRegistry     *llm.BackendRegistry
+	mlxBackendRegistryOnce sync.Once
+	mlxController          *mlxcontrol.Controller
+)
+
+// defaultBackendRegistry builds the registry on first use: the MLX backend
+// always goes first (preserving ollmlx's historical behavior for anything
+// it would have claimed before external backends existed), followed by
+// every external backend ollmlx could load a config for. A backend config
+// that fails to parse is logged and skipped rather than aborting startup.
+func defaultBackendRegistry() *llm.BackendRegistry {
+	mlxBackendRegistryOnce.Do(func() {
+		registry := llm.NewBackendRegistry()
+		mlxManager := llm.NewMLXModelManager()
+		mlxManager.StartCrawler()
+		registry.Register(llm.NewMLXBackend(mlxManager, mlxRunnerPool))
+
+		mlxController = mlxcontrol.NewController(mlxManager)
+		mlxController.Run(context.Background())
+		mlxRunnerPool.OnFreshRunner = func(string) { mlxController.TriggerReconcile() }
+
+		dir, err := llm.ExternalBackendsDir()
+		if err != nil {
+			slog.Warn("failed to resolve external backends dir", "error", err)
+		} else if configs, err := llm.LoadExternalBackendConfigs(dir); err != nil {
+			slog.Warn("failed to load external backend configs", "dir", dir, "error", err)
+		} else {
+			for _, cfg := range configs {
+				registry.Register(llm.NewExternalBackend(cfg))
+			}
+		}
+
+		mlxBackendRegistry = registry
+	})
+	return mlxBackendRegistry
+}
+
+// defaultMLXController returns the process's single running
+// mlxcontrol.Controller, starting the control plane (via
+// defaultBackendRegistry) on first use if it hasn't been already.
+func defaultMLXController() *mlxcontrol.Controller {
+	defaultBackendRegistry()
+	return mlxController
+}
+
+// ProbeMLXBackends launches every configured external backend and waits for
+// it to report healthy, so `ollmlx serve` can fail fast on a misconfigured
+// backend at startup instead of at its first request. The built-in MLX
+// backend isn't probed here: it only spawns its subprocess lazily, on first
+// Acquire from mlxRunnerPool.
+func ProbeMLXBackends(ctx context.Context) []error {
+	var errs []error
+	for _, b := range defaultBackendRegistry().All() {
+		external, ok := b.(*llm.ExternalBackend)
+		if !ok {
+			continue
+		}
+		if err := external.Probe(ctx); err != nil {
+			errs = append(errs, fmt.Errorf("backend %s: %w", b.Name(), err))
+		}
 	}
+	return errs
+}
 
-	if strings.Contains(strings.ToLower(modelName), "-mlx") {
-		return true
+// startMLXRunnerFunc and loadMLXModelFunc are indirected through package
+// variables so tests can substitute a fake runner; production code should
+// never reassign them.
+var startMLXRunnerFunc = startMLXRunner
+var loadMLXModelFunc = loadMLXModel
+
+// startMLXRunner acquires a warm runner for modelName from mlxRunnerPool,
+// starting and loading one if none is already running. The returned
+// handle's Close releases it back to the pool instead of terminating the
+// subprocess; the pool decides on its own when a runner actually gets shut
+// down (see llm.MLXRunnerPool's idle eviction).
+func startMLXRunner(ctx context.Context, modelName string) (mlxRunnerHandle, error) {
+	manager := llm.NewMLXModelManager()
+	modelPath := manager.GetModelPath(modelName)
+
+	handle, err := mlxRunnerPool.Acquire(ctx, modelPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to acquire mlx runner: %w", err)
+	}
+	return &pooledRunnerHandle{handle: handle}, nil
+}
+
+// pooledRunnerHandle adapts an llm.RunnerHandle to the mlxRunnerHandle
+// interface generateMLXModel expects.
+type pooledRunnerHandle struct {
+	handle *llm.RunnerHandle
+}
+
+// Load is a no-op: the pool only ever hands out a runner already loaded
+// with the model path it was acquired for.
+func (h *pooledRunnerHandle) Load(ctx context.Context, modelPath string) error {
+	return nil
+}
+
+func (h *pooledRunnerHandle) Predict(ctx context.Context, prompt string, maxTokens int, temperature, topP float32, stop []string, grammar string, images [][]byte) (<-chan mlxrunner.Token, error) {
+	return h.handle.Server().Predict(ctx, prompt, maxTokens, temperature, topP, stop, grammar, images)
+}
+
+func (h *pooledRunnerHandle) Health(ctx context.Context) error {
+	return h.handle.Server().Health(ctx)
+}
+
+func (h *pooledRunnerHandle) SupportsGrammar(ctx context.Context) bool {
+	return h.handle.Server().SupportsGrammar(ctx)
+}
+
+// Close releases the handle back to mlxRunnerPool; it does not terminate
+// the backend subprocess.
+func (h *pooledRunnerHandle) Close() error {
+	h.handle.Release()
+	return nil
+}
+
+// waitForMLXRunner polls rs until it reports healthy or ctx is done.
+func waitForMLXRunner(ctx context.Context, rs mlxRunnerHandle) error {
+	var lastErr error
+	for {
+		if err := rs.Health(ctx); err == nil {
+			return nil
+		} else {
+			lastErr = err
+		}
+
+		select {
+		case <-ctx.Done():
+			if lastErr != nil {
+				return fmt.Errorf("mlx runner did not become healthy: %w", lastErr)
+			}
+			return ctx.Err()
+		case <-time.After(100 * time.Millisecond):
+		}
 	}
+}
+
+// loadMLXModel asks rs to load modelPath.
+func loadMLXModel(ctx context.Context, rs mlxRunnerHandle, modelPath string) error {
+	return rs.Load(ctx, modelPath)
+}
 
-	// Check if model exists in MLX cache
+// acquireMLXRunner resolves localName's on-disk model path, acquires a
+// runner for it (starting and loading one if none is already warm), and
+// waits for it to report healthy. Callers must Close() the returned handle
+// when done with it, whether or not a later step in the request fails.
+func acquireMLXRunner(ctx context.Context, localName string) (mlxRunnerHandle, string, error) {
 	manager := llm.NewMLXModelManager()
-	return manager.ModelExists(modelName)
+	modelPath := manager.GetModelPath(localName)
+
+	rs, err := startMLXRunnerFunc(ctx, localName)
+	if err != nil {
+		return nil, "", err
+	}
+
+	if err := waitForMLXRunner(ctx, rs); err != nil {
+		rs.Close()
+		return nil, "", err
+	}
+
+	if err := loadMLXModelFunc(ctx, rs, modelPath); err != nil {
+		rs.Close()
+		return nil, "", err
+	}
+
+	return rs, modelPath, nil
+}
+
+// resolveMLXGrammar compiles format into a grammar and returns its source,
+// or "" if format is empty or rs can't enforce one. modelName is only used
+// to name the model in the fallback warning.
+func resolveMLXGrammar(ctx context.Context, rs mlxRunnerHandle, format json.RawMessage, modelName string) (string, error) {
+	g, err := mlxrunner.CompileFormat(format)
+	if err != nil {
+		return "", err
+	}
+	if g == nil {
+		return "", nil
+	}
+	if !rs.SupportsGrammar(ctx) {
+		slog.Warn("mlx runner does not support grammars; falling back to unconstrained decoding", "model", modelName)
+		return "", nil
+	}
+	return g.Source, nil
 }
 
-// generateMLXModel handles generation requests for MLX models
+// streamMLXResponses relays every value sent on ch to c's response body as
+// one NDJSON line each, flushing after every line so a client reading the
+// stream sees tokens as they arrive rather than buffered until ch closes.
+func streamMLXResponses(c *gin.Context, ch <-chan any) {
+	c.Header("Content-Type", "application/x-ndjson")
+	c.Stream(func(w io.Writer) bool {
+		v, ok := <-ch
+		if !ok {
+			return false
+		}
+		data, err := json.Marshal(v)
+		if err != nil {
+			slog.Error("failed to marshal mlx stream response", "error", err)
+			return false
+		}
+		data = append(data, '\n')
+		w.Write(data)
+		return true
+	})
+}
+
+// generateMLXModel handles generation requests for MLX models by acquiring
+// a warm gRPC-backed MLX runner from the pool and relaying its completion
+// back as the response body, either as a single api.GenerateResponse or, if
+// req.Stream isn't explicitly false, as NDJSON-streamed partial responses.
 func (s *Server) generateMLXModel(c *gin.Context, req *api.GenerateRequest) {
-	// Get the model manager
 	manager := llm.NewMLXModelManager()
 
 	// Convert HuggingFace URL format to local directory name
@@ -151,87 +467,358 @@ func (s *Server) generateMLXModel(c *gin.Context, req *api.GenerateRequest) {
 		return
 	}
 
-	// Get model info
-	_, err := manager.GetModelInfo(localName)
+	ctx := c.Request.Context()
+
+	rs, _, err := acquireMLXRunner(ctx, localName)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
+	defer rs.Close()
 
-	// Get the model path
-	modelPath := manager.GetModelPath(localName)
+	grammar, err := resolveMLXGrammar(ctx, rs, req.Format, req.Model)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("invalid format: %s", err)})
+		return
+	}
 
-	// For MLX models, we need to start the MLX runner and communicate with it
-	// The MLX runner is an HTTP server that wraps the Python MLX backend
-	
-	// Start the MLX runner for this model
-	// We'll use a simple approach: start the runner as a subprocess
-	// and communicate with it via HTTP
-	
-	// Create a temporary directory for the runner
-	runnerDir, err := os.MkdirTemp("", "ollmlx-runner-*")
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to create runner directory"})
+	prompt, images, err := prepareVisionInput(req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
-	defer os.RemoveAll(runnerDir)
-
-	// Start the MLX runner subprocess
-	cmd := exec.Command(
-		"go", "run", "./runner/mlxrunner/runner.go",
-		"-model", modelPath,
-		"-port", "0", // Let the runner choose a port
-	)
-	
-	// Start the command
-	if err := cmd.Start(); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("failed to start MLX runner: %v", err)})
+
+	opts := api.DefaultOptions()
+	if err := opts.FromMap(req.Options); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
-	
-	// Wait a bit for the runner to start
-	time.Sleep(2 * time.Second)
-	
-	// For now, return a simple response indicating MLX generation is working
-	// In a real implementation, we would:
-	// 1. Communicate with the MLX runner via HTTP
-	// 2. Send load and completion requests
-	// 3. Stream responses back to the client
-	
-	c.JSON(http.StatusOK, gin.H{
-		"model": req.Model,
-		"response": "MLX model generation is working! This is a placeholder response.",
-		"done": true,
-		"done_reason": "complete",
-	})
-	
-	// Clean up the runner process
-	cmd.Process.Kill()
+
+	tokens, err := rs.Predict(ctx, prompt, opts.NumPredict, opts.Temperature, opts.TopP, opts.Stop, grammar, images)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	if req.Stream != nil && !*req.Stream {
+		resp, err := collectMLXGenerateResponse(ctx, req.Model, tokens)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, resp)
+		return
+	}
+
+	ch := make(chan any)
+	go func() {
+		defer close(ch)
+		streamMLXGenerateTokens(ctx, req.Model, tokens, ch)
+	}()
+	streamMLXResponses(c, ch)
+}
+
+// collectMLXGenerateResponse drains tokens into a single non-streaming
+// api.GenerateResponse. A cancelled ctx still surfaces whatever usage the
+// backend had counted before the stream broke, rather than discarding it
+// behind a bare error.
+func collectMLXGenerateResponse(ctx context.Context, model string, tokens <-chan mlxrunner.Token) (api.GenerateResponse, error) {
+	var response strings.Builder
+	doneReason := "stop"
+	var promptEvalCount, evalCount int
+	var promptEvalDuration, evalDuration time.Duration
+	for tok := range tokens {
+		if tok.Err != nil {
+			if ctx.Err() != nil {
+				doneReason = "canceled"
+				break
+			}
+			return api.GenerateResponse{}, tok.Err
+		}
+		response.WriteString(tok.Text)
+		promptEvalCount = tok.PromptTokens
+		evalCount = tok.CompletionTokens
+		promptEvalDuration = tok.PromptEvalDuration
+		evalDuration = tok.EvalDuration
+		if tok.Done && tok.DoneReason != "" {
+			doneReason = tok.DoneReason
+		}
+	}
+
+	return api.GenerateResponse{
+		Model:      model,
+		Response:   response.String(),
+		Done:       true,
+		DoneReason: doneReason,
+		Metrics: api.Metrics{
+			PromptEvalCount:    promptEvalCount,
+			PromptEvalDuration: promptEvalDuration,
+			EvalCount:          evalCount,
+			EvalDuration:       evalDuration,
+		},
+	}, nil
+}
+
+// streamMLXGenerateTokens relays each token from tokens as a partial,
+// incremental-text api.GenerateResponse on ch, followed by one final
+// Done:true response carrying the accumulated usage metrics. The caller
+// closes ch once this returns.
+func streamMLXGenerateTokens(ctx context.Context, model string, tokens <-chan mlxrunner.Token, ch chan<- any) {
+	doneReason := "stop"
+	var promptEvalCount, evalCount int
+	var promptEvalDuration, evalDuration time.Duration
+	for tok := range tokens {
+		if tok.Err != nil {
+			if ctx.Err() != nil {
+				doneReason = "canceled"
+				break
+			}
+			ch <- gin.H{"error": tok.Err.Error()}
+			return
+		}
+		promptEvalCount = tok.PromptTokens
+		evalCount = tok.CompletionTokens
+		promptEvalDuration = tok.PromptEvalDuration
+		evalDuration = tok.EvalDuration
+		if tok.Done && tok.DoneReason != "" {
+			doneReason = tok.DoneReason
+		}
+		if tok.Text != "" {
+			ch <- api.GenerateResponse{Model: model, Response: tok.Text, Done: false}
+		}
+	}
+
+	ch <- api.GenerateResponse{
+		Model:      model,
+		Done:       true,
+		DoneReason: doneReason,
+		Metrics: api.Metrics{
+			PromptEvalCount:    promptEvalCount,
+			PromptEvalDuration: promptEvalDuration,
+			EvalCount:          evalCount,
+			EvalDuration:       evalDuration,
+		},
+	}
 }
 
-// parseParameterCount converts parameter size string to number
+// chatMLXModel handles chat requests for MLX models: it renders
+// req.Messages through the model's chat template, acquires a warm runner
+// the same way generateMLXModel does, and relays the completion back as
+// one api.ChatResponse or, if req.Stream isn't explicitly false, as
+// NDJSON-streamed partial responses. A model's free-form output is
+// salvaged into req.Tools calls via parseToolCallsFromText when no
+// grammar constrained the generation.
+func (s *Server) chatMLXModel(c *gin.Context, req *api.ChatRequest) {
+	manager := llm.NewMLXModelManager()
+
+	localName := strings.ReplaceAll(req.Model, "/", "_")
+
+	if !manager.ModelExists(localName) {
+		c.JSON(http.StatusNotFound, gin.H{"error": fmt.Sprintf("model '%s' not found", req.Model)})
+		return
+	}
+
+	ctx := c.Request.Context()
+
+	rs, _, err := acquireMLXRunner(ctx, localName)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	defer rs.Close()
+
+	grammar, err := resolveMLXGrammar(ctx, rs, req.Format, req.Model)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("invalid format: %s", err)})
+		return
+	}
+
+	prompt := formatChatPromptWithModel(req.Messages, req.Tools, req.Model)
+
+	opts := api.DefaultOptions()
+	if err := opts.FromMap(req.Options); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	tokens, err := rs.Predict(ctx, prompt, opts.NumPredict, opts.Temperature, opts.TopP, opts.Stop, grammar, nil)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	hasTools := len(req.Tools) > 0
+
+	if req.Stream != nil && !*req.Stream {
+		resp, err := collectMLXChatResponse(ctx, req.Model, tokens, hasTools)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, resp)
+		return
+	}
+
+	ch := make(chan any)
+	go func() {
+		defer close(ch)
+		streamMLXChatTokens(ctx, req.Model, tokens, hasTools, ch)
+	}()
+	streamMLXResponses(c, ch)
+}
+
+// collectMLXChatResponse drains tokens into a single non-streaming
+// api.ChatResponse. When hasTools is set, the accumulated text is first
+// tried as a tool call via parseToolCallsFromText; on a match the message
+// carries ToolCalls instead of free-form Content, matching how Ollama
+// reports a completed tool call.
+func collectMLXChatResponse(ctx context.Context, model string, tokens <-chan mlxrunner.Token, hasTools bool) (api.ChatResponse, error) {
+	var text strings.Builder
+	doneReason := "stop"
+	var promptEvalCount, evalCount int
+	var promptEvalDuration, evalDuration time.Duration
+	for tok := range tokens {
+		if tok.Err != nil {
+			if ctx.Err() != nil {
+				doneReason = "canceled"
+				break
+			}
+			return api.ChatResponse{}, tok.Err
+		}
+		text.WriteString(tok.Text)
+		promptEvalCount = tok.PromptTokens
+		evalCount = tok.CompletionTokens
+		promptEvalDuration = tok.PromptEvalDuration
+		evalDuration = tok.EvalDuration
+		if tok.Done && tok.DoneReason != "" {
+			doneReason = tok.DoneReason
+		}
+	}
+
+	message := api.Message{Role: "assistant", Content: text.String()}
+	if hasTools {
+		if calls, ok := parseToolCallsFromText(text.String()); ok {
+			message = api.Message{Role: "assistant", ToolCalls: calls}
+		}
+	}
+
+	return api.ChatResponse{
+		Model:      model,
+		Message:    message,
+		Done:       true,
+		DoneReason: doneReason,
+		Metrics: api.Metrics{
+			PromptEvalCount:    promptEvalCount,
+			PromptEvalDuration: promptEvalDuration,
+			EvalCount:          evalCount,
+			EvalDuration:       evalDuration,
+		},
+	}, nil
+}
+
+// streamMLXChatTokens relays each token from tokens as a partial
+// api.ChatResponse carrying that token's text on ch. The accumulated text
+// is still buffered so that, once the stream ends, a tool call can be
+// salvaged from it the same way collectMLXChatResponse does: the final
+// Done:true response's message carries ToolCalls in place of Content when
+// one was found. The caller closes ch once this returns.
+func streamMLXChatTokens(ctx context.Context, model string, tokens <-chan mlxrunner.Token, hasTools bool, ch chan<- any) {
+	var text strings.Builder
+	doneReason := "stop"
+	var promptEvalCount, evalCount int
+	var promptEvalDuration, evalDuration time.Duration
+	for tok := range tokens {
+		if tok.Err != nil {
+			if ctx.Err() != nil {
+				doneReason = "canceled"
+				break
+			}
+			ch <- gin.H{"error": tok.Err.Error()}
+			return
+		}
+		text.WriteString(tok.Text)
+		promptEvalCount = tok.PromptTokens
+		evalCount = tok.CompletionTokens
+		promptEvalDuration = tok.PromptEvalDuration
+		evalDuration = tok.EvalDuration
+		if tok.Done && tok.DoneReason != "" {
+			doneReason = tok.DoneReason
+		}
+		if tok.Text != "" {
+			ch <- api.ChatResponse{
+				Model:   model,
+				Message: api.Message{Role: "assistant", Content: tok.Text},
+				Done:    false,
+			}
+		}
+	}
+
+	message := api.Message{Role: "assistant"}
+	if hasTools {
+		if calls, ok := parseToolCallsFromText(text.String()); ok {
+			message.ToolCalls = calls
+		}
+	}
+
+	ch <- api.ChatResponse{
+		Model:      model,
+		Message:    message,
+		Done:       true,
+		DoneReason: doneReason,
+		Metrics: api.Metrics{
+			PromptEvalCount:    promptEvalCount,
+			PromptEvalDuration: promptEvalDuration,
+			EvalCount:          evalCount,
+			EvalDuration:       evalDuration,
+		},
+	}
+}
+
+// parameterCountPattern matches an optionally comma-grouped decimal number
+// followed by an optional magnitude suffix: a letter (k/m/b/t) or a spelled
+// word (thousand/million/billion/trillion), e.g. "7b", "1.7b", "135 million",
+// "7,000,000,000".
+var parameterCountPattern = regexp.MustCompile(`^([0-9][0-9,]*(?:\.[0-9]+)?)\s*([a-z]*)$`)
+
+// parameterCountSuffixes maps a parameterCountPattern suffix to its
+// multiplier; "" (no suffix) means the number is already the raw count.
+var parameterCountSuffixes = map[string]float64{
+	"":         1,
+	"k":        1_000,
+	"thousand": 1_000,
+	"m":        1_000_000,
+	"million":  1_000_000,
+	"b":        1_000_000_000,
+	"billion":  1_000_000_000,
+	"t":        1_000_000_000_000,
+	"trillion": 1_000_000_000_000,
+}
+
+// parseParameterCount is the fallback used when
+// llm.MLXModelManager.ParameterCount couldn't compute an exact count from
+// safetensors shards (e.g. the model has none on disk yet): it parses
+// whatever parameter-size string ended up on MLXModelInfo, in any of the
+// formats HuggingFace model cards use.
 func parseParameterCount(paramSize string) int64 {
 	paramSize = strings.ToLower(strings.TrimSpace(paramSize))
-	
-	// Handle common formats like "7b", "7 billion", "7,000,000,000"
-	if strings.HasSuffix(paramSize, "b") {
-		// Remove "b" suffix
-		numStr := strings.TrimSuffix(paramSize, "b")
-		
-		// Handle "7b" format
-		if numStr == "7" {
-			return 7_000_000_000
-		} else if numStr == "135m" {
-			return 135_000_000
-		} else if numStr == "1.7b" {
-			return 1_700_000_000
-		} else if numStr == "3b" {
-			return 3_000_000_000
-		} else if numStr == "1b" {
-			return 1_000_000_000
-		}
-	}
-	
-	// Default to 0 if we can't parse it
-	return 0
+	if paramSize == "" {
+		return 0
+	}
+
+	matches := parameterCountPattern.FindStringSubmatch(paramSize)
+	if matches == nil {
+		return 0
+	}
+
+	multiplier, ok := parameterCountSuffixes[matches[2]]
+	if !ok {
+		return 0
+	}
+
+	value, err := strconv.ParseFloat(strings.ReplaceAll(matches[1], ",", ""), 64)
+	if err != nil {
+		return 0
+	}
+
+	return int64(value * multiplier)
 }
@@ -0,0 +1,286 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/ollama/ollama/api"
+)
+
+// ChatTemplateType identifies which prompt-formatting and tool-calling
+// convention an MLX model expects. MLX community repos don't reliably ship
+// a chat_template.jinja ollmlx can introspect ahead of time, so it's
+// detected from the model name instead.
+type ChatTemplateType string
+
+const (
+	TemplateQwen    ChatTemplateType = "qwen"
+	TemplateLlama   ChatTemplateType = "llama"
+	TemplateMistral ChatTemplateType = "mistral"
+	TemplatePhi     ChatTemplateType = "phi"
+	TemplateGemma   ChatTemplateType = "gemma"
+	TemplateSmolLM  ChatTemplateType = "smollm"
+	TemplateChatML  ChatTemplateType = "chatml"
+)
+
+// detectMLXChatTemplate guesses a model's chat template family from its
+// name. Order matters: SmolLM is checked ahead of the generic ChatML
+// fallback it would otherwise share markers with.
+func detectMLXChatTemplate(modelName string) ChatTemplateType {
+	lower := strings.ToLower(modelName)
+
+	switch {
+	case strings.Contains(lower, "smollm"):
+		return TemplateSmolLM
+	case strings.Contains(lower, "qwen"):
+		return TemplateQwen
+	case strings.Contains(lower, "llama"):
+		return TemplateLlama
+	case strings.Contains(lower, "mistral"), strings.Contains(lower, "mixtral"):
+		return TemplateMistral
+	case strings.Contains(lower, "phi"):
+		return TemplatePhi
+	case strings.Contains(lower, "gemma"):
+		return TemplateGemma
+	default:
+		return TemplateChatML
+	}
+}
+
+// getImageToken returns the placeholder text a model's chat template
+// expects in place of image N of a multi-image prompt. Qwen2-VL numbers
+// its image tokens; every other family (and anything unrecognized) uses a
+// single bare <image> token regardless of position.
+func getImageToken(modelName string, imageIndex int) string {
+	lower := strings.ToLower(modelName)
+	if strings.Contains(lower, "qwen2-vl") || strings.Contains(lower, "qwen2_vl") {
+		return fmt.Sprintf("<image_%d>", imageIndex+1)
+	}
+	return "<image>"
+}
+
+// formatChatPromptWithModel renders messages (with an optional tool prompt
+// prepended as a system turn) into the raw prompt text the model's chat
+// template expects, ending with an open assistant turn ready for
+// generation. When modelName has a tokenizer_config.json with a
+// chat_template, that authoritative template is used instead of the
+// hardcoded per-family formatting below; the hardcoded tables remain the
+// fallback for models missing the field, or whose template this package's
+// Jinja2 subset can't render.
+func formatChatPromptWithModel(messages []api.Message, tools api.Tools, modelName string) string {
+	if block := toolPromptBlock(tools); block != "" {
+		messages = append([]api.Message{{Role: "system", Content: block}}, messages...)
+	}
+
+	if tmpl, bosToken, eosToken, ok := loadMLXChatTemplate(modelName); ok {
+		if rendered, err := renderChatTemplate(tmpl, messages, true, bosToken, eosToken); err == nil {
+			return rendered
+		}
+	}
+
+	return formatChatPromptByFamily(messages, modelName)
+}
+
+// formatChatPromptByFamily renders messages using the hardcoded
+// ChatTemplateType markers, for models with no usable chat_template of
+// their own.
+func formatChatPromptByFamily(messages []api.Message, modelName string) string {
+	var b strings.Builder
+	switch detectMLXChatTemplate(modelName) {
+	case TemplateLlama:
+		b.WriteString("<|begin_of_text|>")
+		for _, m := range messages {
+			fmt.Fprintf(&b, "<|start_header_id|>%s<|end_header_id|>\n\n%s<|eot_id|>", m.Role, m.Content)
+		}
+		b.WriteString("<|start_header_id|>assistant<|end_header_id|>\n\n")
+	case TemplateMistral:
+		b.WriteString("<s>")
+		for _, m := range messages {
+			if m.Role == "user" {
+				fmt.Fprintf(&b, "[INST] %s [/INST]", m.Content)
+			} else {
+				b.WriteString(m.Content)
+			}
+		}
+	case TemplatePhi:
+		// Phi-3's official template always opens with a system turn, even
+		// an empty one, rather than omitting it when no system message was
+		// given.
+		b.WriteString("<|system|>\n<|end|>\n")
+		for _, m := range messages {
+			fmt.Fprintf(&b, "<|%s|>\n%s<|end|>\n", m.Role, m.Content)
+		}
+		b.WriteString("<|assistant|>\n")
+	case TemplateGemma:
+		for _, m := range messages {
+			role := m.Role
+			if role == "assistant" {
+				role = "model"
+			}
+			fmt.Fprintf(&b, "<start_of_turn>%s\n%s<end_of_turn>\n", role, m.Content)
+		}
+		b.WriteString("<start_of_turn>model\n")
+	default: // Qwen, SmolLM, and plain ChatML all share the <|im_start|> markers.
+		for _, m := range messages {
+			fmt.Fprintf(&b, "<|im_start|>%s\n%s<|im_end|>\n", m.Role, m.Content)
+		}
+		b.WriteString("<|im_start|>assistant\n")
+	}
+
+	return b.String()
+}
+
+// toolPromptBlock renders tools into a system-turn instruction telling the
+// model how to request a call, in the absence of a grammar constraining
+// its output to that shape (see mlxrunner.CompileToolCallGrammar for the
+// alternative). Returns "" when there are no tools to offer.
+func toolPromptBlock(tools api.Tools) string {
+	if len(tools) == 0 {
+		return ""
+	}
+
+	var b strings.Builder
+	b.WriteString("You have access to the following tools. To call one, respond with exactly one JSON object of the form {\"tool_calls\":[{\"name\":\"<tool>\",\"arguments\":{...}}]} and nothing else.\n\n")
+	for _, tool := range tools {
+		fmt.Fprintf(&b, "- %s: %s\n", tool.Function.Name, tool.Function.Description)
+	}
+	return b.String()
+}
+
+// parseToolCallsFromText salvages tool calls from a model's free-form
+// output when no grammar constrained the generation. It recognizes the
+// shapes MLX models tend to emit: an OpenAI-style
+// {"tool_calls":[{"function":{"name":...,"arguments":...}}]} wrapper, the
+// same list flattened to {"name":...,"arguments":...} entries, a single
+// direct call, or the tool name used as the call's only object key. It
+// also tolerates the call being embedded in surrounding prose.
+func parseToolCallsFromText(text string) ([]api.ToolCall, bool) {
+	obj, ok := decodeJSONObject(text)
+	if !ok {
+		return nil, false
+	}
+	return toolCallsFromObject(obj)
+}
+
+func decodeJSONObject(text string) (map[string]any, bool) {
+	var obj map[string]any
+	if err := json.Unmarshal([]byte(strings.TrimSpace(text)), &obj); err == nil {
+		return obj, true
+	}
+
+	if candidate, ok := extractJSONObject(text); ok {
+		if err := json.Unmarshal([]byte(candidate), &obj); err == nil {
+			return obj, true
+		}
+	}
+	return nil, false
+}
+
+// extractJSONObject finds the first balanced {...} substring in text,
+// tracking string literals so braces inside them don't throw off the
+// count. Used to salvage a tool call the model wrapped in prose.
+func extractJSONObject(text string) (string, bool) {
+	start := strings.IndexByte(text, '{')
+	if start < 0 {
+		return "", false
+	}
+
+	depth := 0
+	inString := false
+	escaped := false
+	for i := start; i < len(text); i++ {
+		ch := text[i]
+		if inString {
+			switch {
+			case escaped:
+				escaped = false
+			case ch == '\\':
+				escaped = true
+			case ch == '"':
+				inString = false
+			}
+			continue
+		}
+
+		switch ch {
+		case '"':
+			inString = true
+		case '{':
+			depth++
+		case '}':
+			depth--
+			if depth == 0 {
+				return text[start : i+1], true
+			}
+		}
+	}
+	return "", false
+}
+
+func toolCallsFromObject(obj map[string]any) ([]api.ToolCall, bool) {
+	if raw, hasToolCalls := obj["tool_calls"]; hasToolCalls {
+		list, ok := raw.([]any)
+		if !ok || len(list) == 0 {
+			return nil, false
+		}
+
+		var calls []api.ToolCall
+		for _, item := range list {
+			entry, ok := item.(map[string]any)
+			if !ok {
+				continue
+			}
+			if call, ok := toolCallFromEntry(entry); ok {
+				calls = append(calls, call)
+			}
+		}
+		if len(calls) == 0 {
+			return nil, false
+		}
+		return calls, true
+	}
+
+	if call, ok := toolCallFromEntry(obj); ok {
+		return []api.ToolCall{call}, true
+	}
+
+	// A single top-level key whose value is itself an object is read as
+	// {"<tool name>": <arguments>}.
+	if len(obj) == 1 {
+		for name, v := range obj {
+			if args, ok := v.(map[string]any); ok {
+				return []api.ToolCall{newToolCall(name, args)}, true
+			}
+		}
+	}
+
+	return nil, false
+}
+
+func toolCallFromEntry(entry map[string]any) (api.ToolCall, bool) {
+	if fn, ok := entry["function"].(map[string]any); ok {
+		name, _ := fn["name"].(string)
+		args, _ := fn["arguments"].(map[string]any)
+		if name == "" {
+			return api.ToolCall{}, false
+		}
+		return newToolCall(name, args), true
+	}
+
+	name, hasName := entry["name"].(string)
+	if !hasName || name == "" {
+		return api.ToolCall{}, false
+	}
+	args, _ := entry["arguments"].(map[string]any)
+	return newToolCall(name, args), true
+}
+
+func newToolCall(name string, args map[string]any) api.ToolCall {
+	return api.ToolCall{
+		Function: api.ToolCallFunction{
+			Name:      name,
+			Arguments: api.ToolCallFunctionArguments(args),
+		},
+	}
+}
@@ -0,0 +1,765 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/ollama/ollama/api"
+	"github.com/ollama/ollama/llm"
+)
+
+// routes_mlx_jinja.go renders the chat_template a model's own
+// tokenizer_config.json carries, instead of guessing its format from the
+// model name. HuggingFace chat templates are Jinja2, but real-world
+// templates only use a small subset of it; this implements exactly that
+// subset: {% for %}, {% if/elif/else %}, {{ expr }}, the `trim` filter,
+// and raise_exception (used by several templates to reject disallowed
+// role orderings). Anything else is a parse or eval error, and
+// formatChatPromptWithModel falls back to the hardcoded per-family
+// formatting when that happens.
+
+// loadMLXChatTemplate reads modelName's tokenizer_config.json (if the
+// model is cached locally and the file is present) and returns its
+// chat_template along with the bos/eos token strings the template may
+// reference. ok is false when there's no usable template, so callers can
+// fall back to the hardcoded family table.
+func loadMLXChatTemplate(modelName string) (tmpl, bosToken, eosToken string, ok bool) {
+	manager := llm.NewMLXModelManager()
+	if !manager.ModelExists(modelName) {
+		return "", "", "", false
+	}
+
+	path := filepath.Join(manager.GetModelPath(modelName), "tokenizer_config.json")
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", "", "", false
+	}
+
+	var raw map[string]any
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return "", "", "", false
+	}
+
+	tmpl, isString := raw["chat_template"].(string)
+	if !isString || strings.TrimSpace(tmpl) == "" {
+		return "", "", "", false
+	}
+
+	return tmpl, tokenStringValue(raw["bos_token"]), tokenStringValue(raw["eos_token"]), true
+}
+
+// tokenStringValue reads a tokenizer_config.json token field, which is
+// either a plain string or (for tokenizers that carry AddedToken metadata)
+// an object with a "content" field.
+func tokenStringValue(v any) string {
+	switch t := v.(type) {
+	case string:
+		return t
+	case map[string]any:
+		if s, ok := t["content"].(string); ok {
+			return s
+		}
+	}
+	return ""
+}
+
+// renderChatTemplate renders tmpl against messages, reporting an error for
+// anything the subset above doesn't cover (unsupported syntax, a
+// raise_exception call, a non-list {% for %} target, ...).
+func renderChatTemplate(tmpl string, messages []api.Message, addGenerationPrompt bool, bosToken, eosToken string) (string, error) {
+	nodes, err := parseJinjaTemplate(tmpl)
+	if err != nil {
+		return "", err
+	}
+
+	env := map[string]any{
+		"messages":              messagesToJinjaValue(messages),
+		"add_generation_prompt": addGenerationPrompt,
+		"bos_token":             bosToken,
+		"eos_token":             eosToken,
+	}
+
+	var b strings.Builder
+	if err := execJinjaNodes(nodes, env, &b); err != nil {
+		return "", err
+	}
+	return b.String(), nil
+}
+
+func messagesToJinjaValue(messages []api.Message) []any {
+	out := make([]any, 0, len(messages))
+	for _, m := range messages {
+		out = append(out, map[string]any{"role": m.Role, "content": m.Content})
+	}
+	return out
+}
+
+// --- parsing ---------------------------------------------------------
+
+type jinjaNode interface{}
+
+type jinjaTextNode string
+
+type jinjaOutputNode struct{ expr string }
+
+type jinjaForNode struct {
+	varName  string
+	iterExpr string
+	body     []jinjaNode
+}
+
+type jinjaIfBranch struct {
+	cond string // "" marks the else branch
+	body []jinjaNode
+}
+
+type jinjaIfNode struct{ branches []jinjaIfBranch }
+
+var jinjaTagPattern = regexp.MustCompile(`\{\{.*?\}\}|\{%.*?%\}`)
+
+type jinjaRawToken struct {
+	kind string // "text", "output", "tag"
+	raw  string
+}
+
+func tokenizeJinja(tmpl string) []jinjaRawToken {
+	var tokens []jinjaRawToken
+	last := 0
+	for _, loc := range jinjaTagPattern.FindAllStringIndex(tmpl, -1) {
+		if loc[0] > last {
+			tokens = append(tokens, jinjaRawToken{"text", tmpl[last:loc[0]]})
+		}
+		match := tmpl[loc[0]:loc[1]]
+		if strings.HasPrefix(match, "{{") {
+			tokens = append(tokens, jinjaRawToken{"output", strings.TrimSpace(match[2 : len(match)-2])})
+		} else {
+			tokens = append(tokens, jinjaRawToken{"tag", strings.TrimSpace(match[2 : len(match)-2])})
+		}
+		last = loc[1]
+	}
+	if last < len(tmpl) {
+		tokens = append(tokens, jinjaRawToken{"text", tmpl[last:]})
+	}
+	return tokens
+}
+
+func parseJinjaTemplate(tmpl string) ([]jinjaNode, error) {
+	tokens := tokenizeJinja(tmpl)
+	nodes, pos, _, _, err := parseJinjaNodesUntil(tokens, 0, nil)
+	if err != nil {
+		return nil, err
+	}
+	if pos != len(tokens) {
+		return nil, fmt.Errorf("unexpected trailing template tag")
+	}
+	return nodes, nil
+}
+
+// parseJinjaNodesUntil parses tokens[pos:], descending into nested
+// for/if blocks, until it reaches a tag whose keyword is in stopKeywords
+// (consuming that tag) or runs out of tokens (only valid when
+// stopKeywords is nil, i.e. at the top level).
+func parseJinjaNodesUntil(tokens []jinjaRawToken, pos int, stopKeywords []string) (nodes []jinjaNode, next int, stopKeyword, stopRaw string, err error) {
+	for pos < len(tokens) {
+		tok := tokens[pos]
+		switch tok.kind {
+		case "text":
+			nodes = append(nodes, jinjaTextNode(tok.raw))
+			pos++
+			continue
+		case "output":
+			nodes = append(nodes, jinjaOutputNode{expr: tok.raw})
+			pos++
+			continue
+		}
+
+		keyword := strings.Fields(tok.raw)[0]
+		for _, sk := range stopKeywords {
+			if keyword == sk {
+				return nodes, pos + 1, keyword, tok.raw, nil
+			}
+		}
+
+		switch keyword {
+		case "for":
+			varName, iterExpr, ferr := parseJinjaForHeader(tok.raw)
+			if ferr != nil {
+				return nil, 0, "", "", ferr
+			}
+			body, nx, _, _, ferr2 := parseJinjaNodesUntil(tokens, pos+1, []string{"endfor"})
+			if ferr2 != nil {
+				return nil, 0, "", "", ferr2
+			}
+			nodes = append(nodes, jinjaForNode{varName: varName, iterExpr: iterExpr, body: body})
+			pos = nx
+		case "if":
+			ifn, nx, ierr := parseJinjaIf(tokens, pos)
+			if ierr != nil {
+				return nil, 0, "", "", ierr
+			}
+			nodes = append(nodes, ifn)
+			pos = nx
+		default:
+			return nil, 0, "", "", fmt.Errorf("unsupported template tag %q", tok.raw)
+		}
+	}
+
+	if stopKeywords != nil {
+		return nil, 0, "", "", fmt.Errorf("missing end tag, expected one of %v", stopKeywords)
+	}
+	return nodes, pos, "", "", nil
+}
+
+func parseJinjaIf(tokens []jinjaRawToken, pos int) (jinjaIfNode, int, error) {
+	cond, err := parseJinjaIfHeader(tokens[pos].raw, "if")
+	if err != nil {
+		return jinjaIfNode{}, 0, err
+	}
+
+	var node jinjaIfNode
+	pos++
+	for {
+		body, next, keyword, raw, err := parseJinjaNodesUntil(tokens, pos, []string{"elif", "else", "endif"})
+		if err != nil {
+			return jinjaIfNode{}, 0, err
+		}
+		node.branches = append(node.branches, jinjaIfBranch{cond: cond, body: body})
+		pos = next
+
+		switch keyword {
+		case "endif":
+			return node, pos, nil
+		case "else":
+			elseBody, next2, _, _, err := parseJinjaNodesUntil(tokens, pos, []string{"endif"})
+			if err != nil {
+				return jinjaIfNode{}, 0, err
+			}
+			node.branches = append(node.branches, jinjaIfBranch{cond: "", body: elseBody})
+			return node, next2, nil
+		case "elif":
+			cond, err = parseJinjaIfHeader(raw, "elif")
+			if err != nil {
+				return jinjaIfNode{}, 0, err
+			}
+		}
+	}
+}
+
+func parseJinjaForHeader(raw string) (varName, iterExpr string, err error) {
+	fields := strings.Fields(raw)
+	if len(fields) < 4 || fields[0] != "for" || fields[2] != "in" {
+		return "", "", fmt.Errorf("malformed for tag: %q", raw)
+	}
+	return fields[1], strings.Join(fields[3:], " "), nil
+}
+
+func parseJinjaIfHeader(raw, keyword string) (string, error) {
+	rest := strings.TrimSpace(strings.TrimPrefix(raw, keyword))
+	if rest == "" {
+		return "", fmt.Errorf("malformed %s tag: %q", keyword, raw)
+	}
+	return rest, nil
+}
+
+// --- expression evaluation --------------------------------------------
+
+var jinjaExprTokenPattern = regexp.MustCompile(`'[^']*'|"[^"]*"|==|!=|\(|\)|\[|\]|,|\||[A-Za-z_][A-Za-z0-9_.]*|[0-9]+`)
+
+type jinjaExprNode interface {
+	eval(env map[string]any) (any, error)
+}
+
+// jinjaRaised signals a template's raise_exception(...) call, so callers
+// can treat it as "this template refuses to render here" distinctly from
+// a plain parse/eval bug.
+type jinjaRaised struct{ message string }
+
+func (e *jinjaRaised) Error() string { return "chat template raised: " + e.message }
+
+func evalJinjaExpr(expr string, env map[string]any) (any, error) {
+	tokens := jinjaExprTokenPattern.FindAllString(expr, -1)
+	p := &jinjaExprParser{tokens: tokens}
+	node, err := p.parseExpression()
+	if err != nil {
+		return nil, err
+	}
+	if p.pos != len(p.tokens) {
+		return nil, fmt.Errorf("unexpected trailing content in expression %q", expr)
+	}
+	return node.eval(env)
+}
+
+type jinjaExprParser struct {
+	tokens []string
+	pos    int
+}
+
+func (p *jinjaExprParser) peek() string {
+	if p.pos < len(p.tokens) {
+		return p.tokens[p.pos]
+	}
+	return ""
+}
+
+func (p *jinjaExprParser) next() string {
+	t := p.peek()
+	p.pos++
+	return t
+}
+
+func (p *jinjaExprParser) parseExpression() (jinjaExprNode, error) {
+	node, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	for p.peek() == "|" {
+		p.next()
+		name := p.next()
+		node = jinjaFilterExpr{inner: node, name: name}
+	}
+	return node, nil
+}
+
+func (p *jinjaExprParser) parseOr() (jinjaExprNode, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for p.peek() == "or" {
+		p.next()
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = jinjaBoolExpr{op: "or", left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *jinjaExprParser) parseAnd() (jinjaExprNode, error) {
+	left, err := p.parseNot()
+	if err != nil {
+		return nil, err
+	}
+	for p.peek() == "and" {
+		p.next()
+		right, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		left = jinjaBoolExpr{op: "and", left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *jinjaExprParser) parseNot() (jinjaExprNode, error) {
+	if p.peek() == "not" {
+		p.next()
+		inner, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		return jinjaNotExpr{inner: inner}, nil
+	}
+	return p.parseComparison()
+}
+
+func (p *jinjaExprParser) parseComparison() (jinjaExprNode, error) {
+	left, err := p.parsePrimary()
+	if err != nil {
+		return nil, err
+	}
+
+	switch p.peek() {
+	case "==", "!=":
+		op := p.next()
+		right, err := p.parsePrimary()
+		if err != nil {
+			return nil, err
+		}
+		return jinjaCompareExpr{op: op, left: left, right: right}, nil
+	case "in":
+		p.next()
+		right, err := p.parsePrimary()
+		if err != nil {
+			return nil, err
+		}
+		return jinjaInExpr{left: left, right: right}, nil
+	case "not":
+		save := p.pos
+		p.next()
+		if p.peek() == "in" {
+			p.next()
+			right, err := p.parsePrimary()
+			if err != nil {
+				return nil, err
+			}
+			return jinjaNotExpr{inner: jinjaInExpr{left: left, right: right}}, nil
+		}
+		p.pos = save
+	}
+	return left, nil
+}
+
+func (p *jinjaExprParser) parsePrimary() (jinjaExprNode, error) {
+	tok := p.peek()
+	switch {
+	case tok == "":
+		return nil, fmt.Errorf("unexpected end of expression")
+	case tok == "(":
+		p.next()
+		inner, err := p.parseExpression()
+		if err != nil {
+			return nil, err
+		}
+		if p.peek() != ")" {
+			return nil, fmt.Errorf("expected ')'")
+		}
+		p.next()
+		return inner, nil
+	case strings.HasPrefix(tok, "'") || strings.HasPrefix(tok, `"`):
+		p.next()
+		return jinjaLiteralExpr{value: tok[1 : len(tok)-1]}, nil
+	case tok == "true" || tok == "True":
+		p.next()
+		return jinjaLiteralExpr{value: true}, nil
+	case tok == "false" || tok == "False":
+		p.next()
+		return jinjaLiteralExpr{value: false}, nil
+	case isJinjaIntLiteral(tok):
+		p.next()
+		n, _ := strconv.Atoi(tok)
+		return jinjaLiteralExpr{value: n}, nil
+	default:
+		p.next()
+		var node jinjaExprNode = jinjaPathExpr{parts: strings.Split(tok, ".")}
+		for p.peek() == "[" {
+			p.next()
+			keyTok := p.next()
+			var key jinjaExprNode
+			switch {
+			case strings.HasPrefix(keyTok, "'") || strings.HasPrefix(keyTok, `"`):
+				key = jinjaLiteralExpr{value: keyTok[1 : len(keyTok)-1]}
+			case isJinjaIntLiteral(keyTok):
+				n, _ := strconv.Atoi(keyTok)
+				key = jinjaLiteralExpr{value: n}
+			default:
+				key = jinjaPathExpr{parts: strings.Split(keyTok, ".")}
+			}
+			if p.peek() != "]" {
+				return nil, fmt.Errorf("expected ']'")
+			}
+			p.next()
+			node = jinjaIndexExpr{target: node, key: key}
+		}
+		if p.peek() == "(" {
+			p.next()
+			var args []jinjaExprNode
+			for p.peek() != ")" && p.peek() != "" {
+				arg, err := p.parseExpression()
+				if err != nil {
+					return nil, err
+				}
+				args = append(args, arg)
+				if p.peek() == "," {
+					p.next()
+				}
+			}
+			if p.peek() != ")" {
+				return nil, fmt.Errorf("expected ')'")
+			}
+			p.next()
+			node = jinjaCallExpr{name: tok, args: args}
+		}
+		return node, nil
+	}
+}
+
+// isJinjaIntLiteral reports whether tok is a bare integer, such as the 0
+// in messages[0] or loop.index == 1.
+func isJinjaIntLiteral(tok string) bool {
+	if tok == "" {
+		return false
+	}
+	_, err := strconv.Atoi(tok)
+	return err == nil
+}
+
+type jinjaLiteralExpr struct{ value any }
+
+func (e jinjaLiteralExpr) eval(map[string]any) (any, error) { return e.value, nil }
+
+type jinjaPathExpr struct{ parts []string }
+
+func (e jinjaPathExpr) eval(env map[string]any) (any, error) {
+	cur, ok := env[e.parts[0]]
+	if !ok {
+		return nil, nil
+	}
+	for _, part := range e.parts[1:] {
+		obj, ok := cur.(map[string]any)
+		if !ok {
+			return nil, fmt.Errorf("cannot access %q on a non-object value", part)
+		}
+		cur = obj[part]
+	}
+	return cur, nil
+}
+
+type jinjaIndexExpr struct{ target, key jinjaExprNode }
+
+func (e jinjaIndexExpr) eval(env map[string]any) (any, error) {
+	target, err := e.target.eval(env)
+	if err != nil {
+		return nil, err
+	}
+	key, err := e.key.eval(env)
+	if err != nil {
+		return nil, err
+	}
+
+	switch t := target.(type) {
+	case map[string]any:
+		keyStr, ok := key.(string)
+		if !ok {
+			return nil, fmt.Errorf("object keys must be strings")
+		}
+		return t[keyStr], nil
+	case []any:
+		idx, ok := key.(int)
+		if !ok {
+			return nil, fmt.Errorf("list indices must be integers")
+		}
+		if idx < 0 || idx >= len(t) {
+			return nil, fmt.Errorf("list index out of range")
+		}
+		return t[idx], nil
+	default:
+		return nil, fmt.Errorf("value is not indexable")
+	}
+}
+
+type jinjaCallExpr struct {
+	name string
+	args []jinjaExprNode
+}
+
+func (e jinjaCallExpr) eval(env map[string]any) (any, error) {
+	if e.name != "raise_exception" {
+		return nil, fmt.Errorf("unsupported function %q", e.name)
+	}
+
+	msg := ""
+	if len(e.args) > 0 {
+		v, err := e.args[0].eval(env)
+		if err != nil {
+			return nil, err
+		}
+		if s, ok := v.(string); ok {
+			msg = s
+		}
+	}
+	return nil, &jinjaRaised{message: msg}
+}
+
+type jinjaFilterExpr struct {
+	inner jinjaExprNode
+	name  string
+}
+
+func (e jinjaFilterExpr) eval(env map[string]any) (any, error) {
+	v, err := e.inner.eval(env)
+	if err != nil {
+		return nil, err
+	}
+	switch e.name {
+	case "trim":
+		s, _ := v.(string)
+		return strings.TrimSpace(s), nil
+	default:
+		return nil, fmt.Errorf("unsupported filter %q", e.name)
+	}
+}
+
+type jinjaBoolExpr struct {
+	op          string
+	left, right jinjaExprNode
+}
+
+func (e jinjaBoolExpr) eval(env map[string]any) (any, error) {
+	l, err := e.left.eval(env)
+	if err != nil {
+		return nil, err
+	}
+	if e.op == "and" && !jinjaTruthy(l) {
+		return false, nil
+	}
+	if e.op == "or" && jinjaTruthy(l) {
+		return true, nil
+	}
+	r, err := e.right.eval(env)
+	if err != nil {
+		return nil, err
+	}
+	return jinjaTruthy(r), nil
+}
+
+type jinjaNotExpr struct{ inner jinjaExprNode }
+
+func (e jinjaNotExpr) eval(env map[string]any) (any, error) {
+	v, err := e.inner.eval(env)
+	if err != nil {
+		return nil, err
+	}
+	return !jinjaTruthy(v), nil
+}
+
+type jinjaCompareExpr struct {
+	op          string
+	left, right jinjaExprNode
+}
+
+func (e jinjaCompareExpr) eval(env map[string]any) (any, error) {
+	l, err := e.left.eval(env)
+	if err != nil {
+		return nil, err
+	}
+	r, err := e.right.eval(env)
+	if err != nil {
+		return nil, err
+	}
+	eq := fmt.Sprintf("%v", l) == fmt.Sprintf("%v", r)
+	if e.op == "!=" {
+		return !eq, nil
+	}
+	return eq, nil
+}
+
+type jinjaInExpr struct{ left, right jinjaExprNode }
+
+func (e jinjaInExpr) eval(env map[string]any) (any, error) {
+	l, err := e.left.eval(env)
+	if err != nil {
+		return nil, err
+	}
+	r, err := e.right.eval(env)
+	if err != nil {
+		return nil, err
+	}
+
+	switch rv := r.(type) {
+	case string:
+		ls, _ := l.(string)
+		return strings.Contains(rv, ls), nil
+	case []any:
+		for _, item := range rv {
+			if fmt.Sprintf("%v", item) == fmt.Sprintf("%v", l) {
+				return true, nil
+			}
+		}
+		return false, nil
+	case map[string]any:
+		ls, _ := l.(string)
+		_, ok := rv[ls]
+		return ok, nil
+	default:
+		return false, nil
+	}
+}
+
+func jinjaTruthy(v any) bool {
+	switch t := v.(type) {
+	case nil:
+		return false
+	case bool:
+		return t
+	case string:
+		return t != ""
+	case int:
+		return t != 0
+	case []any:
+		return len(t) > 0
+	case map[string]any:
+		return len(t) > 0
+	default:
+		return true
+	}
+}
+
+// --- execution ---------------------------------------------------------
+
+func execJinjaNodes(nodes []jinjaNode, env map[string]any, b *strings.Builder) error {
+	for _, n := range nodes {
+		if err := execJinjaNode(n, env, b); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func execJinjaNode(n jinjaNode, env map[string]any, b *strings.Builder) error {
+	switch node := n.(type) {
+	case jinjaTextNode:
+		b.WriteString(string(node))
+		return nil
+	case jinjaOutputNode:
+		val, err := evalJinjaExpr(node.expr, env)
+		if err != nil {
+			return err
+		}
+		b.WriteString(jinjaStringify(val))
+		return nil
+	case jinjaForNode:
+		iterVal, err := evalJinjaExpr(node.iterExpr, env)
+		if err != nil {
+			return err
+		}
+		items, ok := iterVal.([]any)
+		if !ok {
+			return fmt.Errorf("cannot iterate over non-list value %q", node.iterExpr)
+		}
+		loopEnv := make(map[string]any, len(env)+1)
+		for k, v := range env {
+			loopEnv[k] = v
+		}
+		for _, item := range items {
+			loopEnv[node.varName] = item
+			if err := execJinjaNodes(node.body, loopEnv, b); err != nil {
+				return err
+			}
+		}
+		return nil
+	case jinjaIfNode:
+		for _, branch := range node.branches {
+			if branch.cond == "" {
+				return execJinjaNodes(branch.body, env, b)
+			}
+			condVal, err := evalJinjaExpr(branch.cond, env)
+			if err != nil {
+				return err
+			}
+			if jinjaTruthy(condVal) {
+				return execJinjaNodes(branch.body, env, b)
+			}
+		}
+		return nil
+	default:
+		return fmt.Errorf("unsupported template node %T", n)
+	}
+}
+
+func jinjaStringify(v any) string {
+	switch t := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return t
+	default:
+		return fmt.Sprintf("%v", t)
+	}
+}
@@ -0,0 +1,600 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ollama/ollama/api"
+	"github.com/ollama/ollama/llm"
+	"github.com/ollama/ollama/runner/mlxrunner"
+)
+
+// This file layers an OpenAI-compatible /v1 surface over MLX models,
+// alongside the native Ollama-shaped handlers in routes_mlx.go and
+// routes_mlx_chat.go. It recognizes requests via IsMLXModelReference and
+// reuses the same runner pool (acquireMLXRunner, resolveMLXGrammar) those
+// handlers do, translating only the request/response shape at the edges.
+
+// mlxOpenAIStopSequences unmarshals an OpenAI "stop" field, which may be
+// either a single string or an array of strings.
+type mlxOpenAIStopSequences []string
+
+func (s *mlxOpenAIStopSequences) UnmarshalJSON(data []byte) error {
+	var single string
+	if err := json.Unmarshal(data, &single); err == nil {
+		if single != "" {
+			*s = []string{single}
+		}
+		return nil
+	}
+
+	var multiple []string
+	if err := json.Unmarshal(data, &multiple); err != nil {
+		return err
+	}
+	*s = multiple
+	return nil
+}
+
+// mlxOpenAIChatMessage is the OpenAI chat message shape; it's translated
+// into api.Message before reaching formatChatPromptWithModel.
+type mlxOpenAIChatMessage struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+}
+
+type mlxOpenAIResponseFormat struct {
+	Type string `json:"type"`
+}
+
+type mlxOpenAIChatCompletionRequest struct {
+	Model          string                   `json:"model"`
+	Messages       []mlxOpenAIChatMessage   `json:"messages"`
+	Stream         bool                     `json:"stream"`
+	Temperature    *float32                 `json:"temperature"`
+	TopP           *float32                 `json:"top_p"`
+	MaxTokens      *int                     `json:"max_tokens"`
+	Stop           mlxOpenAIStopSequences   `json:"stop"`
+	ResponseFormat *mlxOpenAIResponseFormat `json:"response_format"`
+}
+
+type mlxOpenAIUsage struct {
+	PromptTokens     int `json:"prompt_tokens"`
+	CompletionTokens int `json:"completion_tokens"`
+	TotalTokens      int `json:"total_tokens"`
+}
+
+type mlxOpenAIChatCompletionChoice struct {
+	Index        int                  `json:"index"`
+	Message      mlxOpenAIChatMessage `json:"message"`
+	FinishReason string               `json:"finish_reason"`
+}
+
+type mlxOpenAIChatCompletionResponse struct {
+	ID      string                          `json:"id"`
+	Object  string                          `json:"object"`
+	Model   string                          `json:"model"`
+	Choices []mlxOpenAIChatCompletionChoice `json:"choices"`
+	Usage   mlxOpenAIUsage                  `json:"usage"`
+}
+
+type mlxOpenAIChatCompletionChunkDelta struct {
+	Role    string `json:"role,omitempty"`
+	Content string `json:"content,omitempty"`
+}
+
+type mlxOpenAIChatCompletionChunkChoice struct {
+	Index        int                               `json:"index"`
+	Delta        mlxOpenAIChatCompletionChunkDelta `json:"delta"`
+	FinishReason *string                            `json:"finish_reason"`
+}
+
+type mlxOpenAIChatCompletionChunk struct {
+	ID      string                               `json:"id"`
+	Object  string                               `json:"object"`
+	Model   string                               `json:"model"`
+	Choices []mlxOpenAIChatCompletionChunkChoice `json:"choices"`
+}
+
+type mlxOpenAICompletionRequest struct {
+	Model       string                 `json:"model"`
+	Prompt      string                 `json:"prompt"`
+	Stream      bool                   `json:"stream"`
+	Temperature *float32               `json:"temperature"`
+	TopP        *float32               `json:"top_p"`
+	MaxTokens   *int                   `json:"max_tokens"`
+	Stop        mlxOpenAIStopSequences `json:"stop"`
+}
+
+type mlxOpenAICompletionChoice struct {
+	Index        int    `json:"index"`
+	Text         string `json:"text"`
+	FinishReason string `json:"finish_reason"`
+}
+
+type mlxOpenAICompletionResponse struct {
+	ID      string                      `json:"id"`
+	Object  string                      `json:"object"`
+	Model   string                      `json:"model"`
+	Choices []mlxOpenAICompletionChoice `json:"choices"`
+	Usage   mlxOpenAIUsage              `json:"usage"`
+}
+
+type mlxOpenAICompletionChunkChoice struct {
+	Index        int     `json:"index"`
+	Text         string  `json:"text"`
+	FinishReason *string `json:"finish_reason"`
+}
+
+type mlxOpenAICompletionChunk struct {
+	ID      string                           `json:"id"`
+	Object  string                           `json:"object"`
+	Model   string                           `json:"model"`
+	Choices []mlxOpenAICompletionChunkChoice `json:"choices"`
+}
+
+// mlxOpenAIEmbeddingInput unmarshals an OpenAI "input" field, which may be
+// either a single string or an array of strings.
+type mlxOpenAIEmbeddingInput []string
+
+func (i *mlxOpenAIEmbeddingInput) UnmarshalJSON(data []byte) error {
+	var single string
+	if err := json.Unmarshal(data, &single); err == nil {
+		*i = []string{single}
+		return nil
+	}
+
+	var multiple []string
+	if err := json.Unmarshal(data, &multiple); err != nil {
+		return err
+	}
+	*i = multiple
+	return nil
+}
+
+type mlxOpenAIEmbeddingRequest struct {
+	Model string                  `json:"model"`
+	Input mlxOpenAIEmbeddingInput `json:"input"`
+}
+
+type mlxOpenAIEmbeddingData struct {
+	Index     int       `json:"index"`
+	Object    string    `json:"object"`
+	Embedding []float32 `json:"embedding"`
+}
+
+type mlxOpenAIEmbeddingResponse struct {
+	Object string                   `json:"object"`
+	Model  string                   `json:"model"`
+	Data   []mlxOpenAIEmbeddingData `json:"data"`
+	Usage  mlxOpenAIUsage           `json:"usage"`
+}
+
+// mlxOpenAIDoneMarker is the terminating SSE payload every OpenAI
+// streaming endpoint ends with.
+const mlxOpenAIDoneMarker = "[DONE]"
+
+// mlxOpenAIErrorResponse shapes an error the way the OpenAI API does, so
+// existing OpenAI SDKs surface it the same way they would a real OpenAI
+// error.
+func mlxOpenAIErrorResponse(message string) gin.H {
+	return gin.H{"error": gin.H{"message": message, "type": "invalid_request_error"}}
+}
+
+// mlxOpenAICompletionID generates an id in OpenAI's "<prefix>-<unique>"
+// shape for a single response or stream.
+func mlxOpenAICompletionID(prefix string) string {
+	return fmt.Sprintf("%s-%d", prefix, time.Now().UnixNano())
+}
+
+// mlxOpenAIFinishReason maps ollmlx's internal done reasons onto the
+// OpenAI-compatible values clients expect. OpenAI has no equivalent of a
+// cancelled generation, so that maps to "stop" like a normal end of turn.
+func mlxOpenAIFinishReason(doneReason string) string {
+	if doneReason == "length" {
+		return "length"
+	}
+	return "stop"
+}
+
+// mlxOpenAIGenerationParams fills in ollmlx's Ollama-style generation
+// defaults for any OpenAI request field left unset.
+func mlxOpenAIGenerationParams(maxTokens *int, temperature, topP *float32, stop mlxOpenAIStopSequences) (int, float32, float32, []string) {
+	opts := api.DefaultOptions()
+	if maxTokens != nil {
+		opts.NumPredict = *maxTokens
+	}
+	if temperature != nil {
+		opts.Temperature = *temperature
+	}
+	if topP != nil {
+		opts.TopP = *topP
+	}
+	return opts.NumPredict, opts.Temperature, opts.TopP, []string(stop)
+}
+
+// writeMLXOpenAIChunk marshals chunk as one SSE "data: ..." line.
+func writeMLXOpenAIChunk(w io.Writer, chunk any) {
+	data, err := json.Marshal(chunk)
+	if err != nil {
+		slog.Error("failed to marshal openai stream chunk", "error", err)
+		return
+	}
+	fmt.Fprintf(w, "data: %s\n\n", data)
+}
+
+// mlxEmbeddingsFunc is indirected through a package variable, mirroring
+// startMLXRunnerFunc and loadMLXModelFunc in routes_mlx.go, so tests can
+// substitute a fake embedder without spawning a real MLX backend.
+var mlxEmbeddingsFunc = mlxEmbeddings
+
+func mlxEmbeddings(ctx context.Context, modelPath string, input []string) ([][]float32, error) {
+	return mlxRunnerPool.Embeddings(ctx, modelPath, input)
+}
+
+// collectMLXOpenAIText drains tokens into accumulated text and usage,
+// preserving whatever usage the backend had counted before a cancelled
+// context broke the stream, the same way collectMLXGenerateResponse does.
+func collectMLXOpenAIText(ctx context.Context, tokens <-chan mlxrunner.Token) (string, mlxOpenAIUsage, string, error) {
+	var text strings.Builder
+	doneReason := "stop"
+	var promptEvalCount, evalCount int
+	for tok := range tokens {
+		if tok.Err != nil {
+			if ctx.Err() != nil {
+				doneReason = "canceled"
+				break
+			}
+			return "", mlxOpenAIUsage{}, "", tok.Err
+		}
+		text.WriteString(tok.Text)
+		promptEvalCount = tok.PromptTokens
+		evalCount = tok.CompletionTokens
+		if tok.Done && tok.DoneReason != "" {
+			doneReason = tok.DoneReason
+		}
+	}
+
+	usage := mlxOpenAIUsage{
+		PromptTokens:     promptEvalCount,
+		CompletionTokens: evalCount,
+		TotalTokens:      promptEvalCount + evalCount,
+	}
+	return text.String(), usage, mlxOpenAIFinishReason(doneReason), nil
+}
+
+// MLXChatCompletionsHandler serves /v1/chat/completions for MLX model
+// references: it renders req.Messages through the model's chat template
+// via formatChatPromptWithModel and relays the completion back as either
+// a single ChatCompletionResponse or, when req.Stream is set, an SSE
+// stream of ChatCompletionChunk events ending in "data: [DONE]".
+func (s *Server) MLXChatCompletionsHandler(c *gin.Context) {
+	var req mlxOpenAIChatCompletionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, mlxOpenAIErrorResponse(err.Error()))
+		return
+	}
+
+	if !IsMLXModelReference(req.Model) {
+		c.JSON(http.StatusNotFound, mlxOpenAIErrorResponse(fmt.Sprintf("model '%s' not found", req.Model)))
+		return
+	}
+
+	localName := strings.ReplaceAll(req.Model, "/", "_")
+	ctx := c.Request.Context()
+
+	rs, _, err := acquireMLXRunner(ctx, localName)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, mlxOpenAIErrorResponse(err.Error()))
+		return
+	}
+	defer rs.Close()
+
+	var format json.RawMessage
+	if req.ResponseFormat != nil && req.ResponseFormat.Type == "json_object" {
+		format = json.RawMessage(`"json"`)
+	}
+	grammar, err := resolveMLXGrammar(ctx, rs, format, req.Model)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, mlxOpenAIErrorResponse(fmt.Sprintf("invalid response_format: %s", err)))
+		return
+	}
+
+	messages := make([]api.Message, len(req.Messages))
+	for i, m := range req.Messages {
+		messages[i] = api.Message{Role: m.Role, Content: m.Content}
+	}
+	prompt := formatChatPromptWithModel(messages, nil, req.Model)
+
+	maxTokens, temperature, topP, stop := mlxOpenAIGenerationParams(req.MaxTokens, req.Temperature, req.TopP, req.Stop)
+
+	tokens, err := rs.Predict(ctx, prompt, maxTokens, temperature, topP, stop, grammar, nil)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, mlxOpenAIErrorResponse(err.Error()))
+		return
+	}
+
+	id := mlxOpenAICompletionID("chatcmpl")
+
+	if !req.Stream {
+		text, usage, finishReason, err := collectMLXOpenAIText(ctx, tokens)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, mlxOpenAIErrorResponse(err.Error()))
+			return
+		}
+		c.JSON(http.StatusOK, mlxOpenAIChatCompletionResponse{
+			ID:     id,
+			Object: "chat.completion",
+			Model:  req.Model,
+			Choices: []mlxOpenAIChatCompletionChoice{{
+				Index:        0,
+				Message:      mlxOpenAIChatMessage{Role: "assistant", Content: text},
+				FinishReason: finishReason,
+			}},
+			Usage: usage,
+		})
+		return
+	}
+
+	streamMLXOpenAIChatCompletion(c, id, req.Model, tokens)
+}
+
+// streamMLXOpenAIChatCompletion relays tokens as OpenAI-style SSE chat
+// completion chunks: a leading role-only chunk, one content-delta chunk
+// per non-empty token, a final chunk carrying finish_reason, and the
+// terminating "data: [DONE]" line OpenAI clients expect.
+func streamMLXOpenAIChatCompletion(c *gin.Context, id, model string, tokens <-chan mlxrunner.Token) {
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+
+	const (
+		stageRole = iota
+		stageTokens
+		stageDone
+	)
+	stage := stageRole
+
+	c.Stream(func(w io.Writer) bool {
+		switch stage {
+		case stageRole:
+			stage = stageTokens
+			writeMLXOpenAIChunk(w, mlxOpenAIChatCompletionChunk{
+				ID: id, Object: "chat.completion.chunk", Model: model,
+				Choices: []mlxOpenAIChatCompletionChunkChoice{{Delta: mlxOpenAIChatCompletionChunkDelta{Role: "assistant"}}},
+			})
+			return true
+		case stageDone:
+			io.WriteString(w, "data: "+mlxOpenAIDoneMarker+"\n\n")
+			return false
+		}
+
+		tok, ok := <-tokens
+		if !ok {
+			stage = stageDone
+			return true
+		}
+
+		if tok.Err != nil {
+			reason := mlxOpenAIFinishReason("canceled")
+			writeMLXOpenAIChunk(w, mlxOpenAIChatCompletionChunk{
+				ID: id, Object: "chat.completion.chunk", Model: model,
+				Choices: []mlxOpenAIChatCompletionChunkChoice{{FinishReason: &reason}},
+			})
+			stage = stageDone
+			return true
+		}
+
+		if tok.Text != "" {
+			writeMLXOpenAIChunk(w, mlxOpenAIChatCompletionChunk{
+				ID: id, Object: "chat.completion.chunk", Model: model,
+				Choices: []mlxOpenAIChatCompletionChunkChoice{{Delta: mlxOpenAIChatCompletionChunkDelta{Content: tok.Text}}},
+			})
+		}
+
+		if tok.Done {
+			reason := mlxOpenAIFinishReason(tok.DoneReason)
+			writeMLXOpenAIChunk(w, mlxOpenAIChatCompletionChunk{
+				ID: id, Object: "chat.completion.chunk", Model: model,
+				Choices: []mlxOpenAIChatCompletionChunkChoice{{FinishReason: &reason}},
+			})
+			stage = stageDone
+		}
+		return true
+	})
+}
+
+// MLXCompletionsHandler serves the legacy /v1/completions endpoint for
+// MLX model references: req.Prompt is sent to the runner as-is, with no
+// chat template applied.
+func (s *Server) MLXCompletionsHandler(c *gin.Context) {
+	var req mlxOpenAICompletionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, mlxOpenAIErrorResponse(err.Error()))
+		return
+	}
+
+	if !IsMLXModelReference(req.Model) {
+		c.JSON(http.StatusNotFound, mlxOpenAIErrorResponse(fmt.Sprintf("model '%s' not found", req.Model)))
+		return
+	}
+
+	localName := strings.ReplaceAll(req.Model, "/", "_")
+	ctx := c.Request.Context()
+
+	rs, _, err := acquireMLXRunner(ctx, localName)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, mlxOpenAIErrorResponse(err.Error()))
+		return
+	}
+	defer rs.Close()
+
+	maxTokens, temperature, topP, stop := mlxOpenAIGenerationParams(req.MaxTokens, req.Temperature, req.TopP, req.Stop)
+
+	tokens, err := rs.Predict(ctx, req.Prompt, maxTokens, temperature, topP, stop, "", nil)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, mlxOpenAIErrorResponse(err.Error()))
+		return
+	}
+
+	id := mlxOpenAICompletionID("cmpl")
+
+	if !req.Stream {
+		text, usage, finishReason, err := collectMLXOpenAIText(ctx, tokens)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, mlxOpenAIErrorResponse(err.Error()))
+			return
+		}
+		c.JSON(http.StatusOK, mlxOpenAICompletionResponse{
+			ID:      id,
+			Object:  "text_completion",
+			Model:   req.Model,
+			Choices: []mlxOpenAICompletionChoice{{Index: 0, Text: text, FinishReason: finishReason}},
+			Usage:   usage,
+		})
+		return
+	}
+
+	streamMLXOpenAICompletion(c, id, req.Model, tokens)
+}
+
+// streamMLXOpenAICompletion relays tokens as OpenAI-style SSE text
+// completion chunks, the legacy-endpoint counterpart of
+// streamMLXOpenAIChatCompletion.
+func streamMLXOpenAICompletion(c *gin.Context, id, model string, tokens <-chan mlxrunner.Token) {
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+
+	done := false
+	c.Stream(func(w io.Writer) bool {
+		if done {
+			io.WriteString(w, "data: "+mlxOpenAIDoneMarker+"\n\n")
+			return false
+		}
+
+		tok, ok := <-tokens
+		if !ok {
+			done = true
+			return true
+		}
+
+		if tok.Err != nil {
+			reason := mlxOpenAIFinishReason("canceled")
+			writeMLXOpenAIChunk(w, mlxOpenAICompletionChunk{
+				ID: id, Object: "text_completion", Model: model,
+				Choices: []mlxOpenAICompletionChunkChoice{{FinishReason: &reason}},
+			})
+			done = true
+			return true
+		}
+
+		if tok.Text != "" {
+			writeMLXOpenAIChunk(w, mlxOpenAICompletionChunk{
+				ID: id, Object: "text_completion", Model: model,
+				Choices: []mlxOpenAICompletionChunkChoice{{Text: tok.Text}},
+			})
+		}
+
+		if tok.Done {
+			reason := mlxOpenAIFinishReason(tok.DoneReason)
+			writeMLXOpenAIChunk(w, mlxOpenAICompletionChunk{
+				ID: id, Object: "text_completion", Model: model,
+				Choices: []mlxOpenAICompletionChunkChoice{{FinishReason: &reason}},
+			})
+			done = true
+		}
+		return true
+	})
+}
+
+// MLXEmbeddingsHandler serves /v1/embeddings for MLX model references via
+// llm.MLXRunnerPool.Embeddings. Usage.prompt_tokens is a whitespace-count
+// approximation: the pool's embedding path doesn't expose the backend's
+// tokenizer, unlike Predict's accounting.
+func (s *Server) MLXEmbeddingsHandler(c *gin.Context) {
+	var req mlxOpenAIEmbeddingRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, mlxOpenAIErrorResponse(err.Error()))
+		return
+	}
+
+	if !IsMLXModelReference(req.Model) {
+		c.JSON(http.StatusNotFound, mlxOpenAIErrorResponse(fmt.Sprintf("model '%s' not found", req.Model)))
+		return
+	}
+
+	manager := llm.NewMLXModelManager()
+	localName := strings.ReplaceAll(req.Model, "/", "_")
+	if !manager.ModelExists(localName) {
+		c.JSON(http.StatusNotFound, mlxOpenAIErrorResponse(fmt.Sprintf("model '%s' not found", req.Model)))
+		return
+	}
+	modelPath := manager.GetModelPath(localName)
+
+	vectors, err := mlxEmbeddingsFunc(c.Request.Context(), modelPath, []string(req.Input))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, mlxOpenAIErrorResponse(err.Error()))
+		return
+	}
+
+	data := make([]mlxOpenAIEmbeddingData, len(vectors))
+	for i, v := range vectors {
+		data[i] = mlxOpenAIEmbeddingData{Index: i, Object: "embedding", Embedding: v}
+	}
+
+	var promptTokens int
+	for _, in := range req.Input {
+		promptTokens += len(strings.Fields(in))
+	}
+
+	c.JSON(http.StatusOK, mlxOpenAIEmbeddingResponse{
+		Object: "list",
+		Model:  req.Model,
+		Data:   data,
+		Usage:  mlxOpenAIUsage{PromptTokens: promptTokens, TotalTokens: promptTokens},
+	})
+}
+
+// mlxOpenAIModel is a single entry in a /v1/models listing.
+type mlxOpenAIModel struct {
+	ID      string `json:"id"`
+	Object  string `json:"object"`
+	Created int64  `json:"created"`
+	OwnedBy string `json:"owned_by"`
+}
+
+type mlxOpenAIModelList struct {
+	Object string           `json:"object"`
+	Data   []mlxOpenAIModel `json:"data"`
+}
+
+// MLXModelsHandler serves /v1/models, listing every cached MLX model in
+// the OpenAI models-list shape. Unlike the other /v1 handlers it isn't
+// scoped to a single model reference, so it doesn't go through
+// IsMLXModelReference at all.
+func (s *Server) MLXModelsHandler(c *gin.Context) {
+	manager := llm.NewMLXModelManager()
+	models, err := manager.ListModels()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, mlxOpenAIErrorResponse(err.Error()))
+		return
+	}
+
+	data := make([]mlxOpenAIModel, len(models))
+	for i, m := range models {
+		data[i] = mlxOpenAIModel{
+			ID:      m.Name,
+			Object:  "model",
+			Created: m.ModifiedAt.Unix(),
+			OwnedBy: "mlx-community",
+		}
+	}
+
+	c.JSON(http.StatusOK, mlxOpenAIModelList{Object: "list", Data: data})
+}
@@ -0,0 +1,258 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/ollama/ollama/runner/mlxrunner"
+)
+
+// TestMLXChatCompletionsHandlerNonStreaming verifies that a non-streaming
+// /v1/chat/completions request against an MLX model reference returns an
+// OpenAI-shaped response with the runner's completion and usage counts.
+func TestMLXChatCompletionsHandlerNonStreaming(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	modelName := "mlx-community/llama-2"
+	newTestMLXModel(t, modelName)
+
+	fake := &fakeMLXRunner{tokens: []mlxrunner.Token{
+		{Text: "Hi there!", Done: true, DoneReason: "stop", PromptTokens: 5, CompletionTokens: 3},
+	}}
+	startMLXRunnerFunc = func(_ context.Context, _ string) (mlxRunnerHandle, error) { return fake, nil }
+	defer func() { startMLXRunnerFunc = startMLXRunner }()
+	loadMLXModelFunc = func(ctx context.Context, rs mlxRunnerHandle, modelPath string) error { return rs.Load(ctx, modelPath) }
+	defer func() { loadMLXModelFunc = loadMLXModel }()
+
+	body, _ := json.Marshal(map[string]any{
+		"model":    modelName,
+		"messages": []map[string]string{{"role": "user", "content": "hello"}},
+	})
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(http.MethodPost, "/v1/chat/completions", bytes.NewReader(body))
+
+	srvInstance := &Server{}
+	srvInstance.MLXChatCompletionsHandler(c)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("unexpected status: got %d body %s", w.Code, w.Body.String())
+	}
+
+	var resp mlxOpenAIChatCompletionResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+
+	if len(resp.Choices) != 1 || resp.Choices[0].Message.Content != "Hi there!" {
+		t.Fatalf("unexpected choices: %+v", resp.Choices)
+	}
+	if resp.Choices[0].FinishReason != "stop" {
+		t.Fatalf("unexpected finish_reason: %q", resp.Choices[0].FinishReason)
+	}
+	if resp.Usage.PromptTokens != 5 || resp.Usage.CompletionTokens != 3 {
+		t.Fatalf("unexpected usage: %+v", resp.Usage)
+	}
+}
+
+// TestMLXChatCompletionsHandlerStreaming verifies that a streaming
+// /v1/chat/completions request emits SSE "data: " chunks ending in
+// "data: [DONE]".
+func TestMLXChatCompletionsHandlerStreaming(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	modelName := "mlx-community/llama-2"
+	newTestMLXModel(t, modelName)
+
+	fake := &fakeMLXRunner{tokens: []mlxrunner.Token{
+		{Text: "Hi"},
+		{Text: " there", Done: true, DoneReason: "stop"},
+	}}
+	startMLXRunnerFunc = func(_ context.Context, _ string) (mlxRunnerHandle, error) { return fake, nil }
+	defer func() { startMLXRunnerFunc = startMLXRunner }()
+	loadMLXModelFunc = func(ctx context.Context, rs mlxRunnerHandle, modelPath string) error { return rs.Load(ctx, modelPath) }
+	defer func() { loadMLXModelFunc = loadMLXModel }()
+
+	body, _ := json.Marshal(map[string]any{
+		"model":    modelName,
+		"messages": []map[string]string{{"role": "user", "content": "hello"}},
+		"stream":   true,
+	})
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(http.MethodPost, "/v1/chat/completions", bytes.NewReader(body))
+
+	srvInstance := &Server{}
+	srvInstance.MLXChatCompletionsHandler(c)
+
+	out := w.Body.String()
+	if !strings.Contains(out, `"content":"Hi"`) {
+		t.Fatalf("expected a content delta chunk for %q, got: %s", "Hi", out)
+	}
+	if !strings.HasSuffix(strings.TrimRight(out, "\n"), "data: "+mlxOpenAIDoneMarker) {
+		t.Fatalf("expected stream to end with the done marker, got: %s", out)
+	}
+}
+
+// TestMLXCompletionsHandlerNonStreaming verifies that a non-streaming
+// legacy /v1/completions request forwards req.Prompt unmodified (no chat
+// template) and returns an OpenAI-shaped text completion.
+func TestMLXCompletionsHandlerNonStreaming(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	modelName := "mlx-community/llama-2"
+	newTestMLXModel(t, modelName)
+
+	fake := &fakeMLXRunner{tokens: []mlxrunner.Token{
+		{Text: "once upon a time", Done: true, DoneReason: "stop"},
+	}}
+	startMLXRunnerFunc = func(_ context.Context, _ string) (mlxRunnerHandle, error) { return fake, nil }
+	defer func() { startMLXRunnerFunc = startMLXRunner }()
+	loadMLXModelFunc = func(ctx context.Context, rs mlxRunnerHandle, modelPath string) error { return rs.Load(ctx, modelPath) }
+	defer func() { loadMLXModelFunc = loadMLXModel }()
+
+	body, _ := json.Marshal(map[string]any{
+		"model":  modelName,
+		"prompt": "tell me a story",
+	})
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(http.MethodPost, "/v1/completions", bytes.NewReader(body))
+
+	srvInstance := &Server{}
+	srvInstance.MLXCompletionsHandler(c)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("unexpected status: got %d body %s", w.Code, w.Body.String())
+	}
+
+	var resp mlxOpenAICompletionResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+
+	if len(resp.Choices) != 1 || resp.Choices[0].Text != "once upon a time" {
+		t.Fatalf("unexpected choices: %+v", resp.Choices)
+	}
+}
+
+// TestMLXEmbeddingsHandler verifies that /v1/embeddings returns one
+// embedding per input string, via the mlxEmbeddingsFunc indirection.
+func TestMLXEmbeddingsHandler(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	modelName := "mlx-community/llama-2"
+	newTestMLXModel(t, modelName)
+
+	var receivedInput []string
+	mlxEmbeddingsFunc = func(_ context.Context, _ string, input []string) ([][]float32, error) {
+		receivedInput = input
+		vectors := make([][]float32, len(input))
+		for i := range input {
+			vectors[i] = []float32{0.1, 0.2, 0.3}
+		}
+		return vectors, nil
+	}
+	defer func() { mlxEmbeddingsFunc = mlxEmbeddings }()
+
+	body, _ := json.Marshal(map[string]any{
+		"model": modelName,
+		"input": []string{"hello world", "goodbye"},
+	})
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(http.MethodPost, "/v1/embeddings", bytes.NewReader(body))
+
+	srvInstance := &Server{}
+	srvInstance.MLXEmbeddingsHandler(c)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("unexpected status: got %d body %s", w.Code, w.Body.String())
+	}
+
+	if len(receivedInput) != 2 {
+		t.Fatalf("expected both input strings to reach the embedder, got %v", receivedInput)
+	}
+
+	var resp mlxOpenAIEmbeddingResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+
+	if len(resp.Data) != 2 {
+		t.Fatalf("expected 2 embeddings, got %d", len(resp.Data))
+	}
+	if resp.Usage.PromptTokens != 3 {
+		t.Fatalf("expected prompt_tokens to count words across inputs, got %d", resp.Usage.PromptTokens)
+	}
+}
+
+// TestMLXModelsHandler verifies that /v1/models lists cached MLX models
+// in the OpenAI models-list shape.
+func TestMLXModelsHandler(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	modelName := "mlx-community/llama-2"
+	newTestMLXModel(t, modelName)
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(http.MethodGet, "/v1/models", nil)
+
+	srvInstance := &Server{}
+	srvInstance.MLXModelsHandler(c)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("unexpected status: got %d body %s", w.Code, w.Body.String())
+	}
+
+	var resp mlxOpenAIModelList
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+
+	if resp.Object != "list" {
+		t.Fatalf("unexpected object: %q", resp.Object)
+	}
+	if len(resp.Data) != 1 || resp.Data[0].ID != modelName {
+		t.Fatalf("unexpected models: %+v", resp.Data)
+	}
+	if resp.Data[0].Object != "model" {
+		t.Fatalf("unexpected object for model entry: %q", resp.Data[0].Object)
+	}
+}
+
+// TestMLXChatCompletionsHandlerUnknownModel verifies that a model
+// reference IsMLXModelReference doesn't recognize is rejected before any
+// runner is acquired.
+func TestMLXChatCompletionsHandlerUnknownModel(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	t.Setenv("OLLAMA_MODELS", t.TempDir())
+
+	body, _ := json.Marshal(map[string]any{
+		"model":    "definitely-not-an-mlx-model",
+		"messages": []map[string]string{{"role": "user", "content": "hi"}},
+	})
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(http.MethodPost, "/v1/chat/completions", bytes.NewReader(body))
+
+	srvInstance := &Server{}
+	srvInstance.MLXChatCompletionsHandler(c)
+
+	if w.Code != http.StatusNotFound {
+		t.Fatalf("expected 404 for an unrecognized model, got %d body %s", w.Code, w.Body.String())
+	}
+}
@@ -1,13 +1,13 @@
 package server
 
 import (
+	"bytes"
 	"context"
-	"fmt"
-	"net"
+	"encoding/json"
+	"errors"
 	"net/http"
 	"net/http/httptest"
 	"os"
-	"os/exec"
 	"path/filepath"
 	"strings"
 	"testing"
@@ -16,28 +16,62 @@ import (
 	"github.com/gin-gonic/gin"
 
 	"github.com/ollama/ollama/api"
-	"github.com/ollama/ollama/envconfig"
+	"github.com/ollama/ollama/llm/mlxcontrol"
+	"github.com/ollama/ollama/runner/mlxrunner"
 )
 
-func TestStartMLXRunnerPropagatesModelsEnv(t *testing.T) {
-	t.Setenv("OLLAMA_MODELS", t.TempDir())
+// fakeMLXRunner is a test double for mlxRunnerHandle, standing in for a real
+// gRPC-speaking mlxrunner.Server so these tests don't need to spawn a Python
+// subprocess.
+type fakeMLXRunner struct {
+	loadedModel     string
+	loadErr         error
+	healthErr       error
+	tokens          []mlxrunner.Token
+	closed          bool
+	supportsGrammar bool
+	receivedGrammar string
+	receivedImages  [][]byte
+}
+
+func (f *fakeMLXRunner) Load(_ context.Context, modelPath string) error {
+	f.loadedModel = modelPath
+	return f.loadErr
+}
 
-	cmd, _, err := startMLXRunner(context.Background(), "test-model")
-	if err != nil {
-		t.Fatalf("startMLXRunner() error = %v", err)
+func (f *fakeMLXRunner) Predict(_ context.Context, _ string, _ int, _, _ float32, _ []string, grammar string, images [][]byte) (<-chan mlxrunner.Token, error) {
+	f.receivedGrammar = grammar
+	f.receivedImages = images
+	out := make(chan mlxrunner.Token, len(f.tokens))
+	for _, tok := range f.tokens {
+		out <- tok
 	}
+	close(out)
+	return out, nil
+}
 
-	expected := fmt.Sprintf("OLLAMA_MODELS=%s", envconfig.Models())
-	found := false
-	for _, env := range cmd.Env {
-		if env == expected {
-			found = true
-			break
-		}
+func (f *fakeMLXRunner) Health(context.Context) error         { return f.healthErr }
+func (f *fakeMLXRunner) SupportsGrammar(context.Context) bool { return f.supportsGrammar }
+func (f *fakeMLXRunner) Close() error                         { f.closed = true; return nil }
+
+func TestStartMLXRunnerDialFailureSurfacesError(t *testing.T) {
+	t.Setenv("OLLAMA_MODELS", t.TempDir())
+	// "true" exits immediately without ever listening on the socket
+	// startMLXRunner dials, so this exercises the dial-failure path without
+	// needing a real MLX backend available in the test environment.
+	t.Setenv("OLLMLX_MLX_BACKEND_CMD", "true")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 500*time.Millisecond)
+	defer cancel()
+
+	rs, err := startMLXRunner(ctx, "test-model")
+	if err == nil {
+		rs.Close()
+		t.Fatalf("expected startMLXRunner to fail when nothing answers the backend socket")
 	}
 
-	if !found {
-		t.Fatalf("expected runner environment to include %q", expected)
+	if !strings.Contains(err.Error(), "failed to dial mlx backend") {
+		t.Fatalf("unexpected error: %v", err)
 	}
 }
 
@@ -63,44 +97,27 @@ func TestGenerateMLXModelUsesLocalName(t *testing.T) {
 		t.Fatalf("failed to write weights: %v", err)
 	}
 
-	listener, err := net.Listen("tcp", "127.0.0.1:0")
-	if err != nil {
-		t.Fatalf("failed to start listener: %v", err)
-	}
-
-	mux := http.NewServeMux()
-	mux.HandleFunc("/health", func(w http.ResponseWriter, _ *http.Request) {
-		w.WriteHeader(http.StatusOK)
-	})
-	mux.HandleFunc("/completion", func(w http.ResponseWriter, _ *http.Request) {
-		w.Header().Set("Content-Type", "application/x-ndjson")
-		fmt.Fprintf(w, `{"content":"ok","done":true,"done_reason":"stop"}\n`)
-	})
-
-	srv := &http.Server{Handler: mux}
-	go srv.Serve(listener)
-	t.Cleanup(func() {
-		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
-		defer cancel()
-		srv.Shutdown(ctx)
-	})
-
-	port := listener.Addr().(*net.TCPAddr).Port
+	fake := &fakeMLXRunner{tokens: []mlxrunner.Token{
+		{
+			Text:               "ok",
+			Done:               true,
+			DoneReason:         "stop",
+			PromptTokens:       3,
+			CompletionTokens:   5,
+			PromptEvalDuration: 10 * time.Millisecond,
+			EvalDuration:       50 * time.Millisecond,
+		},
+	}}
 
 	var startedModel string
-	startMLXRunnerFunc = func(ctx context.Context, modelName string) (*exec.Cmd, int, error) {
+	startMLXRunnerFunc = func(_ context.Context, modelName string) (mlxRunnerHandle, error) {
 		startedModel = modelName
-		return exec.CommandContext(ctx, "true"), port, nil
+		return fake, nil
 	}
 	defer func() { startMLXRunnerFunc = startMLXRunner }()
 
-	var loadedModel string
-	loadMLXModelFunc = func(_ context.Context, _ *http.Client, p int, modelName string) error {
-		if p != port {
-			t.Fatalf("unexpected port: got %d want %d", p, port)
-		}
-		loadedModel = modelName
-		return nil
+	loadMLXModelFunc = func(ctx context.Context, rs mlxRunnerHandle, modelPath string) error {
+		return rs.Load(ctx, modelPath)
 	}
 	defer func() { loadMLXModelFunc = loadMLXModel }()
 
@@ -122,33 +139,198 @@ func TestGenerateMLXModelUsesLocalName(t *testing.T) {
 		t.Fatalf("runner received %q, want %q", startedModel, localName)
 	}
 
-	if loadedModel != localName {
-		t.Fatalf("loader received %q, want %q", loadedModel, localName)
+	expectedPath := filepath.Join(modelsRoot, localName)
+	if fake.loadedModel != expectedPath {
+		t.Fatalf("loader received %q, want %q", fake.loadedModel, expectedPath)
+	}
+
+	if !fake.closed {
+		t.Fatalf("expected runner to be closed after the request")
+	}
+
+	var resp api.GenerateResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+
+	if resp.PromptEvalCount != 3 || resp.EvalCount != 5 {
+		t.Fatalf("expected usage counts to propagate, got prompt_eval_count=%d eval_count=%d", resp.PromptEvalCount, resp.EvalCount)
+	}
+
+	if resp.PromptEvalDuration != 10*time.Millisecond || resp.EvalDuration != 50*time.Millisecond {
+		t.Fatalf("expected durations to propagate, got prompt_eval_duration=%v eval_duration=%v", resp.PromptEvalDuration, resp.EvalDuration)
 	}
 }
 
-func TestWaitForMLXRunnerPropagatesHealthError(t *testing.T) {
-	listener, err := net.Listen("tcp", "127.0.0.1:0")
-	if err != nil {
-		t.Fatalf("failed to create listener: %v", err)
+// TestGenerateMLXModelSurfacesPartialUsageOnCancellation verifies that a
+// context cancellation mid-stream still reports whatever usage the runner
+// had counted before the stream broke, instead of discarding it behind a
+// bare error response.
+func TestGenerateMLXModelSurfacesPartialUsageOnCancellation(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	modelName := "mlx-community/llama-2"
+	localName := strings.ReplaceAll(modelName, "/", "_")
+
+	modelsRoot := t.TempDir()
+	t.Setenv("OLLAMA_MODELS", modelsRoot)
+
+	modelDir := filepath.Join(modelsRoot, localName)
+	if err := os.MkdirAll(modelDir, 0o755); err != nil {
+		t.Fatalf("failed to create model directory: %v", err)
 	}
+	if err := os.WriteFile(filepath.Join(modelDir, "config.json"), []byte("{}"), 0o644); err != nil {
+		t.Fatalf("failed to write config: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	fake := &fakeMLXRunner{tokens: []mlxrunner.Token{
+		{Text: "partial", PromptTokens: 4, CompletionTokens: 2, PromptEvalDuration: 5 * time.Millisecond, EvalDuration: 8 * time.Millisecond},
+		{Done: true, Err: context.Canceled},
+	}}
 
-	server := &http.Server{
-		Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-			http.Error(w, "backend unhealthy", http.StatusServiceUnavailable)
-		}),
+	startMLXRunnerFunc = func(_ context.Context, _ string) (mlxRunnerHandle, error) { return fake, nil }
+	defer func() { startMLXRunnerFunc = startMLXRunner }()
+
+	loadMLXModelFunc = func(ctx context.Context, rs mlxRunnerHandle, modelPath string) error {
+		return rs.Load(ctx, modelPath)
 	}
+	defer func() { loadMLXModelFunc = loadMLXModel }()
 
-	go server.Serve(listener)
-	defer server.Shutdown(context.Background())
+	stream := false
+	req := &api.GenerateRequest{Model: modelName, Prompt: "Hello", Stream: &stream}
 
-	ctx, cancel := context.WithTimeout(context.Background(), 1200*time.Millisecond)
-	defer cancel()
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(http.MethodPost, "/api/generate", nil).WithContext(ctx)
+
+	cancel()
+
+	srvInstance := &Server{}
+	srvInstance.generateMLXModel(c, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected cancellation to still return a response, got %d body %s", w.Code, w.Body.String())
+	}
 
-	client := &http.Client{Timeout: 500 * time.Millisecond}
-	port := listener.Addr().(*net.TCPAddr).Port
+	var resp api.GenerateResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+
+	if resp.DoneReason != "canceled" {
+		t.Fatalf("expected done_reason %q, got %q", "canceled", resp.DoneReason)
+	}
+
+	if resp.PromptEvalCount != 4 || resp.EvalCount != 2 {
+		t.Fatalf("expected partial usage counts to survive cancellation, got prompt_eval_count=%d eval_count=%d", resp.PromptEvalCount, resp.EvalCount)
+	}
+}
+
+func newTestMLXModel(t *testing.T, modelName string) string {
+	t.Helper()
+
+	localName := strings.ReplaceAll(modelName, "/", "_")
+	modelsRoot := t.TempDir()
+	t.Setenv("OLLAMA_MODELS", modelsRoot)
+
+	modelDir := filepath.Join(modelsRoot, localName)
+	if err := os.MkdirAll(modelDir, 0o755); err != nil {
+		t.Fatalf("failed to create model directory: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(modelDir, "config.json"), []byte("{}"), 0o644); err != nil {
+		t.Fatalf("failed to write config: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(modelDir, "model.safetensors"), []byte{}, 0o644); err != nil {
+		t.Fatalf("failed to write weights: %v", err)
+	}
+	return localName
+}
+
+// TestGenerateMLXModelCompilesGrammarWhenSupported verifies that a
+// format:json request is compiled into a grammar and forwarded to a runner
+// that reports grammar support.
+func TestGenerateMLXModelCompilesGrammarWhenSupported(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	modelName := "mlx-community/llama-2"
+	newTestMLXModel(t, modelName)
+
+	fake := &fakeMLXRunner{
+		supportsGrammar: true,
+		tokens:          []mlxrunner.Token{{Text: `{"a":1}`, Done: true, DoneReason: "stop"}},
+	}
+	startMLXRunnerFunc = func(_ context.Context, _ string) (mlxRunnerHandle, error) { return fake, nil }
+	defer func() { startMLXRunnerFunc = startMLXRunner }()
+	loadMLXModelFunc = func(ctx context.Context, rs mlxRunnerHandle, modelPath string) error { return rs.Load(ctx, modelPath) }
+	defer func() { loadMLXModelFunc = loadMLXModel }()
 
-	err = waitForMLXRunner(ctx, client, port)
+	stream := false
+	req := &api.GenerateRequest{Model: modelName, Prompt: "Hello", Stream: &stream, Format: []byte(`"json"`)}
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(http.MethodPost, "/api/generate", nil)
+
+	srvInstance := &Server{}
+	srvInstance.generateMLXModel(c, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("unexpected status: got %d body %s", w.Code, w.Body.String())
+	}
+
+	if fake.receivedGrammar == "" {
+		t.Fatalf("expected a compiled grammar to reach the runner")
+	}
+	if !strings.Contains(fake.receivedGrammar, "root ::=") {
+		t.Fatalf("expected grammar to define a root rule, got %q", fake.receivedGrammar)
+	}
+}
+
+// TestGenerateMLXModelFallsBackWhenGrammarUnsupported verifies that a
+// format:json request against a runner that doesn't support grammars still
+// completes, without a grammar being sent.
+func TestGenerateMLXModelFallsBackWhenGrammarUnsupported(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	modelName := "mlx-community/llama-2"
+	newTestMLXModel(t, modelName)
+
+	fake := &fakeMLXRunner{
+		supportsGrammar: false,
+		tokens:          []mlxrunner.Token{{Text: "free form", Done: true, DoneReason: "stop"}},
+	}
+	startMLXRunnerFunc = func(_ context.Context, _ string) (mlxRunnerHandle, error) { return fake, nil }
+	defer func() { startMLXRunnerFunc = startMLXRunner }()
+	loadMLXModelFunc = func(ctx context.Context, rs mlxRunnerHandle, modelPath string) error { return rs.Load(ctx, modelPath) }
+	defer func() { loadMLXModelFunc = loadMLXModel }()
+
+	stream := false
+	req := &api.GenerateRequest{Model: modelName, Prompt: "Hello", Stream: &stream, Format: []byte(`"json"`)}
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(http.MethodPost, "/api/generate", nil)
+
+	srvInstance := &Server{}
+	srvInstance.generateMLXModel(c, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("unexpected status: got %d body %s", w.Code, w.Body.String())
+	}
+
+	if fake.receivedGrammar != "" {
+		t.Fatalf("expected no grammar when the runner doesn't support one, got %q", fake.receivedGrammar)
+	}
+}
+
+func TestWaitForMLXRunnerPropagatesHealthError(t *testing.T) {
+	fake := &fakeMLXRunner{healthErr: errors.New("backend unhealthy")}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 300*time.Millisecond)
+	defer cancel()
+
+	err := waitForMLXRunner(ctx, fake)
 	if err == nil {
 		t.Fatalf("expected waitForMLXRunner to fail")
 	}
@@ -158,6 +340,150 @@ func TestWaitForMLXRunnerPropagatesHealthError(t *testing.T) {
 	}
 }
 
+// TestGenerateMLXModelStreamsPartialResponses verifies that a generate
+// request whose Stream field is unset (the api default) produces
+// NDJSON-streamed partial responses, ending in one Done:true response
+// carrying the final usage counts.
+func TestGenerateMLXModelStreamsPartialResponses(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	modelName := "mlx-community/llama-2"
+	newTestMLXModel(t, modelName)
+
+	fake := &fakeMLXRunner{tokens: []mlxrunner.Token{
+		{Text: "Hel"},
+		{Text: "lo", Done: true, DoneReason: "stop", PromptTokens: 2, CompletionTokens: 2, EvalDuration: 5 * time.Millisecond},
+	}}
+	startMLXRunnerFunc = func(_ context.Context, _ string) (mlxRunnerHandle, error) { return fake, nil }
+	defer func() { startMLXRunnerFunc = startMLXRunner }()
+	loadMLXModelFunc = func(ctx context.Context, rs mlxRunnerHandle, modelPath string) error { return rs.Load(ctx, modelPath) }
+	defer func() { loadMLXModelFunc = loadMLXModel }()
+
+	req := &api.GenerateRequest{Model: modelName, Prompt: "Hello"}
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(http.MethodPost, "/api/generate", nil)
+
+	srvInstance := &Server{}
+	srvInstance.generateMLXModel(c, req)
+
+	lines := strings.Split(strings.TrimSpace(w.Body.String()), "\n")
+	if len(lines) != 3 {
+		t.Fatalf("expected 3 NDJSON lines (2 partial + 1 final), got %d: %q", len(lines), w.Body.String())
+	}
+
+	var first api.GenerateResponse
+	if err := json.Unmarshal([]byte(lines[0]), &first); err != nil {
+		t.Fatalf("failed to decode first line: %v", err)
+	}
+	if first.Response != "Hel" || first.Done {
+		t.Fatalf("unexpected first partial response: %+v", first)
+	}
+
+	var last api.GenerateResponse
+	if err := json.Unmarshal([]byte(lines[len(lines)-1]), &last); err != nil {
+		t.Fatalf("failed to decode final line: %v", err)
+	}
+	if !last.Done || last.DoneReason != "stop" || last.EvalCount != 2 {
+		t.Fatalf("unexpected final response: %+v", last)
+	}
+}
+
+// TestChatMLXModelNonStreaming verifies that a non-streaming chat request
+// renders req.Messages through the model's chat template and returns the
+// runner's completion as the assistant message, with usage counts
+// propagated the same way generateMLXModel does.
+func TestChatMLXModelNonStreaming(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	modelName := "mlx-community/llama-2"
+	newTestMLXModel(t, modelName)
+
+	fake := &fakeMLXRunner{tokens: []mlxrunner.Token{
+		{Text: "Hi there!", Done: true, DoneReason: "stop", PromptTokens: 4, CompletionTokens: 3},
+	}}
+	startMLXRunnerFunc = func(_ context.Context, _ string) (mlxRunnerHandle, error) { return fake, nil }
+	defer func() { startMLXRunnerFunc = startMLXRunner }()
+	loadMLXModelFunc = func(ctx context.Context, rs mlxRunnerHandle, modelPath string) error { return rs.Load(ctx, modelPath) }
+	defer func() { loadMLXModelFunc = loadMLXModel }()
+
+	stream := false
+	req := &api.ChatRequest{
+		Model:    modelName,
+		Messages: []api.Message{{Role: "user", Content: "hello"}},
+		Stream:   &stream,
+	}
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(http.MethodPost, "/api/chat", nil)
+
+	srvInstance := &Server{}
+	srvInstance.chatMLXModel(c, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("unexpected status: got %d body %s", w.Code, w.Body.String())
+	}
+
+	var resp api.ChatResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+
+	if resp.Message.Role != "assistant" || resp.Message.Content != "Hi there!" {
+		t.Fatalf("unexpected message: %+v", resp.Message)
+	}
+	if resp.PromptEvalCount != 4 || resp.EvalCount != 3 {
+		t.Fatalf("expected usage counts to propagate, got prompt_eval_count=%d eval_count=%d", resp.PromptEvalCount, resp.EvalCount)
+	}
+}
+
+// TestChatMLXModelParsesToolCall verifies that, when tools are offered and
+// no grammar constrained the generation, a tool-call-shaped JSON response
+// is salvaged into resp.Message.ToolCalls instead of being returned as raw
+// text content.
+func TestChatMLXModelParsesToolCall(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	modelName := "mlx-community/llama-2"
+	newTestMLXModel(t, modelName)
+
+	fake := &fakeMLXRunner{tokens: []mlxrunner.Token{
+		{Text: `{"name":"get_weather","arguments":{"city":"Paris"}}`, Done: true, DoneReason: "stop"},
+	}}
+	startMLXRunnerFunc = func(_ context.Context, _ string) (mlxRunnerHandle, error) { return fake, nil }
+	defer func() { startMLXRunnerFunc = startMLXRunner }()
+	loadMLXModelFunc = func(ctx context.Context, rs mlxRunnerHandle, modelPath string) error { return rs.Load(ctx, modelPath) }
+	defer func() { loadMLXModelFunc = loadMLXModel }()
+
+	stream := false
+	req := &api.ChatRequest{
+		Model:    modelName,
+		Messages: []api.Message{{Role: "user", Content: "weather in paris?"}},
+		Tools: api.Tools{
+			{Function: api.ToolFunction{Name: "get_weather", Description: "look up the weather"}},
+		},
+		Stream: &stream,
+	}
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(http.MethodPost, "/api/chat", nil)
+
+	srvInstance := &Server{}
+	srvInstance.chatMLXModel(c, req)
+
+	var resp api.ChatResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+
+	if len(resp.Message.ToolCalls) != 1 || resp.Message.ToolCalls[0].Function.Name != "get_weather" {
+		t.Fatalf("expected a parsed get_weather tool call, got %+v", resp.Message.ToolCalls)
+	}
+}
+
 // TestParseParameterCount tests the parameter count parsing function
 func TestParseParameterCount(t *testing.T) {
 	tests := []struct {
@@ -413,6 +739,34 @@ func TestFormatChatPromptWithModel(t *testing.T) {
 	}
 }
 
+// TestFormatChatPromptWithModelPrefersOwnTemplate verifies that a model
+// whose tokenizer_config.json carries a chat_template has that template
+// rendered instead of the hardcoded per-family formatting, using a
+// fixture template whose output marker belongs to no family default.
+func TestFormatChatPromptWithModelPrefersOwnTemplate(t *testing.T) {
+	modelName := "mlx-community/custom-template-model"
+	localName := newTestMLXModel(t, modelName)
+
+	tokenizerConfig := `{
+		"chat_template": "{% for message in messages %}<<<{{ message['role'] }}>>>{{ message['content'] | trim }}<<<end>>>{% endfor %}{% if add_generation_prompt %}<<<go>>>{% endif %}",
+		"bos_token": "<s>",
+		"eos_token": "</s>"
+	}`
+	modelsRoot := os.Getenv("OLLAMA_MODELS")
+	configPath := filepath.Join(modelsRoot, localName, "tokenizer_config.json")
+	if err := os.WriteFile(configPath, []byte(tokenizerConfig), 0o644); err != nil {
+		t.Fatalf("failed to write tokenizer_config.json: %v", err)
+	}
+
+	messages := []api.Message{{Role: "user", Content: "  hello  "}}
+	result := formatChatPromptWithModel(messages, nil, modelName)
+
+	want := "<<<user>>>hello<<<end>>><<<go>>>"
+	if result != want {
+		t.Fatalf("formatChatPromptWithModel with a custom chat_template = %q, want %q", result, want)
+	}
+}
+
 // TestToolPromptBlock tests tool prompt generation
 func TestToolPromptBlock(t *testing.T) {
 	// Test with nil tools
@@ -497,3 +851,61 @@ func BenchmarkParseToolCallsFromText(b *testing.B) {
 		}
 	}
 }
+
+// TestMLXStateHandler verifies that GET /api/mlx/state reports the actual
+// cached models with an empty desired manifest, and that PUT persists a
+// new desired manifest and reconciles before responding.
+func TestMLXStateHandler(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	modelName := "mlx-community/llama-2"
+	newTestMLXModel(t, modelName)
+
+	srvInstance := &Server{}
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(http.MethodGet, "/api/mlx/state", nil)
+	srvInstance.MLXStateHandler(c)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("unexpected status: got %d body %s", w.Code, w.Body.String())
+	}
+
+	var resp mlxStateResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if len(resp.Desired.Models) != 0 {
+		t.Fatalf("expected no desired models yet, got %+v", resp.Desired.Models)
+	}
+	if len(resp.Actual) != 1 || resp.Actual[0].Name != modelName {
+		t.Fatalf("unexpected actual models: %+v", resp.Actual)
+	}
+
+	// Declare the already-cached model as desired (and pinned): reconcile
+	// should converge without needing a pull or an eviction.
+	body, _ := json.Marshal(mlxcontrol.DesiredState{
+		Models: []mlxcontrol.DesiredModel{{Name: modelName, Pinned: true}},
+	})
+
+	w = httptest.NewRecorder()
+	c, _ = gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(http.MethodPut, "/api/mlx/state", bytes.NewReader(body))
+	c.Request.Header.Set("Content-Type", "application/json")
+	srvInstance.MLXStateHandler(c)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("unexpected status: got %d body %s", w.Code, w.Body.String())
+	}
+
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("failed to decode PUT response: %v", err)
+	}
+	if len(resp.Desired.Models) != 1 || resp.Desired.Models[0].Name != modelName {
+		t.Fatalf("unexpected desired models after PUT: %+v", resp.Desired.Models)
+	}
+	if len(resp.Actual) != 1 || resp.Actual[0].Name != modelName {
+		t.Fatalf("expected the pinned model to remain cached, got: %+v", resp.Actual)
+	}
+}
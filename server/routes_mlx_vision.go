@@ -0,0 +1,215 @@
+package server
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	_ "image/jpeg" // register JPEG decoding alongside PNG so image.Decode can sniff either
+	"image/png"
+	"strings"
+
+	"github.com/ollama/ollama/api"
+)
+
+// imagePreset describes how a ChatTemplateType's vision tower wants input
+// images prepared before they reach the MLX backend. LLaVA and PaliGemma
+// train on a single fixed square crop, Pixtral caps the longest side
+// instead of cropping, and Qwen2-VL patches images at a multiple of its
+// ViT patch size rather than forcing a fixed resolution.
+type imagePreset struct {
+	// size is the target square crop side for fixed-resolution models (0
+	// for models that don't use one).
+	size int
+	// maxLongestSide caps the longest side for models that resize instead
+	// of cropping (0 to disable).
+	maxLongestSide int
+	// patchMultiple rounds width/height up to the nearest multiple of this
+	// many pixels, matching the model's ViT patch size (0 to disable).
+	patchMultiple int
+}
+
+// imagePresets is the per-model preprocessor registry the vision pipeline
+// looks up by ChatTemplateType. Templates with no vision variant in
+// practice (Phi, SmolLM, plain ChatML) fall back to imageFallbackPreset.
+var imagePresets = map[ChatTemplateType]imagePreset{
+	TemplateQwen:    {maxLongestSide: 1024, patchMultiple: 28}, // Qwen2-VL dynamic patching
+	TemplateLlama:   {size: 336},                               // Llama's CLIP-style vision variants
+	TemplateMistral: {maxLongestSide: 1024, patchMultiple: 16}, // Pixtral
+	TemplateGemma:   {size: 224},                               // PaliGemma's SigLIP tower
+}
+
+var imageFallbackPreset = imagePreset{size: 336} // LLaVA's 336px crop
+
+func presetForTemplate(tmpl ChatTemplateType) imagePreset {
+	if p, ok := imagePresets[tmpl]; ok {
+		return p
+	}
+	return imageFallbackPreset
+}
+
+// decodeImage decodes raw image bytes as either PNG or JPEG. callers get
+// api.GenerateRequest.Images entries already base64-decoded by
+// api.ImageData's JSON unmarshaling, so this only has to sniff the image
+// container format.
+func decodeImage(data []byte) (image.Image, error) {
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode image: %w", err)
+	}
+	return img, nil
+}
+
+// resizeForPreset applies p to img: a fixed square center crop, a
+// longest-side cap (optionally rounded to a patch multiple), a bare
+// patch-multiple rounding, or img unchanged if p sets nothing.
+func resizeForPreset(img image.Image, p imagePreset) image.Image {
+	switch {
+	case p.size > 0:
+		return resizeAndCenterCrop(img, p.size, p.size)
+	case p.maxLongestSide > 0:
+		bounds := img.Bounds()
+		w, h := scaleToLongestSide(bounds.Dx(), bounds.Dy(), p.maxLongestSide)
+		if p.patchMultiple > 0 {
+			w = roundUpToMultiple(w, p.patchMultiple)
+			h = roundUpToMultiple(h, p.patchMultiple)
+		}
+		return resizeExact(img, w, h)
+	case p.patchMultiple > 0:
+		bounds := img.Bounds()
+		w := roundUpToMultiple(bounds.Dx(), p.patchMultiple)
+		h := roundUpToMultiple(bounds.Dy(), p.patchMultiple)
+		return resizeExact(img, w, h)
+	default:
+		return img
+	}
+}
+
+// resizeExact nearest-neighbor scales img to exactly w x h.
+func resizeExact(img image.Image, w, h int) image.Image {
+	if w <= 0 || h <= 0 {
+		return img
+	}
+
+	src := img.Bounds()
+	sw, sh := src.Dx(), src.Dy()
+	dst := image.NewRGBA(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		sy := src.Min.Y + y*sh/h
+		for x := 0; x < w; x++ {
+			sx := src.Min.X + x*sw/w
+			dst.Set(x, y, img.At(sx, sy))
+		}
+	}
+	return dst
+}
+
+// resizeAndCenterCrop scales img so it fully covers a w x h box, then
+// center-crops to exactly that size, matching how CLIP/SigLIP-style
+// preprocessors (LLaVA's 336px crop, PaliGemma's 224px crop) prepare
+// training data.
+func resizeAndCenterCrop(img image.Image, w, h int) image.Image {
+	bounds := img.Bounds()
+	sw, sh := bounds.Dx(), bounds.Dy()
+	if sw == 0 || sh == 0 {
+		return img
+	}
+
+	scale := float64(w) / float64(sw)
+	if hs := float64(h) / float64(sh); hs > scale {
+		scale = hs
+	}
+	scaledW := int(float64(sw)*scale + 0.5)
+	scaledH := int(float64(sh)*scale + 0.5)
+	scaled := resizeExact(img, scaledW, scaledH)
+
+	left := (scaledW - w) / 2
+	top := (scaledH - h) / 2
+	cropped := image.NewRGBA(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			cropped.Set(x, y, scaled.At(left+x, top+y))
+		}
+	}
+	return cropped
+}
+
+// scaleToLongestSide scales (w, h) down so its longest side is at most
+// max, preserving aspect ratio. It never scales up.
+func scaleToLongestSide(w, h, max int) (int, int) {
+	longest := w
+	if h > longest {
+		longest = h
+	}
+	if longest <= max {
+		return w, h
+	}
+	scale := float64(max) / float64(longest)
+	return int(float64(w)*scale + 0.5), int(float64(h)*scale + 0.5)
+}
+
+// roundUpToMultiple rounds v up to the nearest positive multiple of m.
+func roundUpToMultiple(v, m int) int {
+	if m <= 0 {
+		return v
+	}
+	if rem := v % m; rem != 0 {
+		v += m - rem
+	}
+	if v == 0 {
+		v = m
+	}
+	return v
+}
+
+func encodeImagePNG(img image.Image) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		return nil, fmt.Errorf("failed to encode image: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// spliceImageTokens prepends one getImageToken placeholder per image ahead
+// of prompt, matching how MLX VLM chat templates expect image placeholders
+// to precede the textual turn they belong to.
+func spliceImageTokens(prompt, modelName string, imageCount int) string {
+	if imageCount == 0 {
+		return prompt
+	}
+
+	var b strings.Builder
+	for i := 0; i < imageCount; i++ {
+		b.WriteString(getImageToken(modelName, i))
+	}
+	b.WriteString(prompt)
+	return b.String()
+}
+
+// prepareVisionInput decodes, resizes, and re-encodes req.Images per the
+// preprocessor registered for the model's ChatTemplateType, and splices
+// the corresponding getImageToken placeholders into req.Prompt. It returns
+// the prompt to send in place of req.Prompt and the encoded image bytes to
+// forward alongside it; when req.Images is empty it returns req.Prompt
+// unchanged and nil images.
+func prepareVisionInput(req *api.GenerateRequest) (string, [][]byte, error) {
+	if len(req.Images) == 0 {
+		return req.Prompt, nil, nil
+	}
+
+	preset := presetForTemplate(detectMLXChatTemplate(req.Model))
+
+	images := make([][]byte, 0, len(req.Images))
+	for i, raw := range req.Images {
+		img, err := decodeImage(raw)
+		if err != nil {
+			return "", nil, fmt.Errorf("image %d: %w", i, err)
+		}
+		encoded, err := encodeImagePNG(resizeForPreset(img, preset))
+		if err != nil {
+			return "", nil, fmt.Errorf("image %d: %w", i, err)
+		}
+		images = append(images, encoded)
+	}
+
+	return spliceImageTokens(req.Prompt, req.Model, len(images)), images, nil
+}
@@ -0,0 +1,223 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"image"
+	"image/color"
+	"image/png"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/ollama/ollama/api"
+	"github.com/ollama/ollama/runner/mlxrunner"
+)
+
+// TestPresetForTemplate tests the per-model vision preprocessor registry,
+// mirroring TestDetectMLXChatTemplate's table-driven style.
+func TestPresetForTemplate(t *testing.T) {
+	tests := []struct {
+		name     string
+		tmpl     ChatTemplateType
+		expected imagePreset
+	}{
+		{"Qwen dynamic patching", TemplateQwen, imagePreset{maxLongestSide: 1024, patchMultiple: 28}},
+		{"Llama fixed crop", TemplateLlama, imagePreset{size: 336}},
+		{"Mistral/Pixtral longest side", TemplateMistral, imagePreset{maxLongestSide: 1024, patchMultiple: 16}},
+		{"Gemma/PaliGemma fixed crop", TemplateGemma, imagePreset{size: 224}},
+		{"Phi has no vision variant, falls back to LLaVA's crop", TemplatePhi, imageFallbackPreset},
+		{"SmolLM has no vision variant, falls back to LLaVA's crop", TemplateSmolLM, imageFallbackPreset},
+		{"ChatML has no vision variant, falls back to LLaVA's crop", TemplateChatML, imageFallbackPreset},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := presetForTemplate(tt.tmpl)
+			if result != tt.expected {
+				t.Errorf("presetForTemplate(%q) = %+v, want %+v", tt.tmpl, result, tt.expected)
+			}
+		})
+	}
+}
+
+// TestResizeForPresetShape tests that each preset variant produces the
+// expected output image shape.
+func TestResizeForPresetShape(t *testing.T) {
+	tests := []struct {
+		name       string
+		preset     imagePreset
+		srcW, srcH int
+		wantW      int
+		wantH      int
+	}{
+		{"fixed square crop", imagePreset{size: 336}, 640, 480, 336, 336},
+		{"longest side cap, no rounding", imagePreset{maxLongestSide: 100}, 400, 200, 100, 50},
+		{"longest side cap under the max is left alone", imagePreset{maxLongestSide: 1024}, 400, 200, 400, 200},
+		{"patch multiple rounding only", imagePreset{patchMultiple: 28}, 50, 50, 56, 56},
+		{"no-op preset", imagePreset{}, 123, 45, 123, 45},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			src := image.NewRGBA(image.Rect(0, 0, tt.srcW, tt.srcH))
+			out := resizeForPreset(src, tt.preset)
+			b := out.Bounds()
+			if b.Dx() != tt.wantW || b.Dy() != tt.wantH {
+				t.Errorf("resizeForPreset(%dx%d, %+v) = %dx%d, want %dx%d", tt.srcW, tt.srcH, tt.preset, b.Dx(), b.Dy(), tt.wantW, tt.wantH)
+			}
+		})
+	}
+}
+
+// TestSpliceImageTokens tests that image placeholders are prepended in
+// order, mirroring TestGetImageToken's matrix.
+func TestSpliceImageTokens(t *testing.T) {
+	tests := []struct {
+		modelName  string
+		imageCount int
+		prompt     string
+		expected   string
+	}{
+		{"llava-hf/llava-1.5-7b-hf", 0, "describe this", "describe this"},
+		{"llava-hf/llava-1.5-7b-hf", 1, "describe this", "<image>describe this"},
+		{"Qwen/Qwen2-VL-7B-Instruct", 2, "compare these", "<image_1><image_2>compare these"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.modelName, func(t *testing.T) {
+			result := spliceImageTokens(tt.prompt, tt.modelName, tt.imageCount)
+			if result != tt.expected {
+				t.Errorf("spliceImageTokens(%q, %q, %d) = %q, want %q", tt.prompt, tt.modelName, tt.imageCount, result, tt.expected)
+			}
+		})
+	}
+}
+
+func newTestPNG(t *testing.T, w, h int) []byte {
+	t.Helper()
+
+	img := image.NewRGBA(image.Rect(0, 0, w, h))
+	fill := color.RGBA{R: 255, A: 255}
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			img.Set(x, y, fill)
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		t.Fatalf("failed to encode test image: %v", err)
+	}
+	return buf.Bytes()
+}
+
+// TestPrepareVisionInputSplicesTokensAndEncodesImages covers the full
+// decode-resize-splice path for a single-image request.
+func TestPrepareVisionInputSplicesTokensAndEncodesImages(t *testing.T) {
+	req := &api.GenerateRequest{
+		Model:  "llava-hf/llava-1.5-7b-hf",
+		Prompt: "what is in this image?",
+		Images: []api.ImageData{newTestPNG(t, 640, 480)},
+	}
+
+	prompt, images, err := prepareVisionInput(req)
+	if err != nil {
+		t.Fatalf("prepareVisionInput returned error: %v", err)
+	}
+
+	if prompt != "<image>what is in this image?" {
+		t.Errorf("prompt = %q, want image token spliced in front", prompt)
+	}
+
+	if len(images) != 1 {
+		t.Fatalf("got %d encoded images, want 1", len(images))
+	}
+
+	decoded, err := decodeImage(images[0])
+	if err != nil {
+		t.Fatalf("failed to decode prepared image: %v", err)
+	}
+	if b := decoded.Bounds(); b.Dx() != 336 || b.Dy() != 336 {
+		t.Errorf("prepared image is %dx%d, want 336x336 (LLaVA's crop)", b.Dx(), b.Dy())
+	}
+}
+
+// TestPrepareVisionInputNoImages verifies the prompt and images are passed
+// through untouched when the request carries no images.
+func TestPrepareVisionInputNoImages(t *testing.T) {
+	req := &api.GenerateRequest{Model: "mlx-community/llama-2", Prompt: "hello"}
+
+	prompt, images, err := prepareVisionInput(req)
+	if err != nil {
+		t.Fatalf("prepareVisionInput returned error: %v", err)
+	}
+	if prompt != "hello" {
+		t.Errorf("prompt = %q, want unchanged", prompt)
+	}
+	if images != nil {
+		t.Errorf("images = %v, want nil", images)
+	}
+}
+
+// TestPrepareVisionInputRejectsUndecodableImage verifies a malformed image
+// surfaces an error instead of being silently dropped.
+func TestPrepareVisionInputRejectsUndecodableImage(t *testing.T) {
+	req := &api.GenerateRequest{
+		Model:  "llava-hf/llava-1.5-7b-hf",
+		Prompt: "describe this",
+		Images: []api.ImageData{[]byte("not an image")},
+	}
+
+	if _, _, err := prepareVisionInput(req); err == nil {
+		t.Fatal("expected an error for undecodable image data")
+	}
+}
+
+// TestGenerateMLXModelForwardsImages verifies that generateMLXModel
+// decodes, resizes, splices, and forwards request images to the runner.
+func TestGenerateMLXModelForwardsImages(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	modelName := "llava-hf/llava-1.5-7b-hf"
+	newTestMLXModel(t, modelName)
+
+	fake := &fakeMLXRunner{tokens: []mlxrunner.Token{{Text: "a cat", Done: true, DoneReason: "stop"}}}
+	startMLXRunnerFunc = func(context.Context, string) (mlxRunnerHandle, error) { return fake, nil }
+	defer func() { startMLXRunnerFunc = startMLXRunner }()
+	loadMLXModelFunc = func(ctx context.Context, rs mlxRunnerHandle, modelPath string) error { return rs.Load(ctx, modelPath) }
+	defer func() { loadMLXModelFunc = loadMLXModel }()
+
+	stream := false
+	req := &api.GenerateRequest{
+		Model:  modelName,
+		Prompt: "what is in this image?",
+		Stream: &stream,
+		Images: []api.ImageData{newTestPNG(t, 640, 480)},
+	}
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(http.MethodPost, "/api/generate", nil)
+
+	srvInstance := &Server{}
+	srvInstance.generateMLXModel(c, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("unexpected status: got %d body %s", w.Code, w.Body.String())
+	}
+
+	if len(fake.receivedImages) != 1 {
+		t.Fatalf("got %d images forwarded to runner, want 1", len(fake.receivedImages))
+	}
+
+	decoded, err := decodeImage(fake.receivedImages[0])
+	if err != nil {
+		t.Fatalf("failed to decode forwarded image: %v", err)
+	}
+	if b := decoded.Bounds(); b.Dx() != 336 || b.Dy() != 336 {
+		t.Errorf("forwarded image is %dx%d, want 336x336 (LLaVA's crop)", b.Dx(), b.Dy())
+	}
+}